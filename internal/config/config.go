@@ -0,0 +1,68 @@
+// Package config loads TOML configs of the bot and the dictionary helper
+// overridden by environment variables.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/knadh/koanf/parsers/toml"
+	"github.com/knadh/koanf/providers/env"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+	"os"
+	"slices"
+	"strings"
+)
+
+const EnvPrefix = "CROC_"
+
+// HelperEnvPrefix is reserved for the dictionary helper configuration.
+const HelperEnvPrefix = EnvPrefix + "HELPER_"
+
+// fileSuffix of a secret variable reads the secret from the named file.
+const fileSuffix = "_file"
+
+// Load reads the TOML file at path and overlays environment variables starting with prefix.
+// CROC_AI__API_KEY sets ai/api_key. For the secret keys, CROC_AI__API_KEY_FILE sets the key
+// to the contents of the named file. Variables starting with one of the skip prefixes are ignored.
+func Load(path, prefix string, secrets []string, skip ...string) (*koanf.Koanf, error) {
+	var kConf = koanf.New("/")
+
+	err := kConf.Load(file.Provider(path), toml.Parser())
+	if err != nil {
+		return nil, err
+	}
+
+	var envErr error
+	envCb := func(key, value string) (string, interface{}) {
+		for _, s := range skip {
+			if strings.HasPrefix(key, s) {
+				return "", nil
+			}
+		}
+
+		key = strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(key, prefix)), "__", "/")
+		secret, found := strings.CutSuffix(key, fileSuffix)
+		if found && slices.Contains(secrets, secret) {
+			data, err := os.ReadFile(value)
+			if err != nil {
+				envErr = errors.Join(envErr, fmt.Errorf("%s: %w", key, err))
+				return "", nil
+			}
+
+			return secret, strings.TrimSpace(string(data))
+		}
+
+		return key, value
+	}
+
+	err = kConf.Load(env.ProviderWithValue(prefix, "/", envCb), nil)
+	if err != nil {
+		return nil, err
+	}
+	if envErr != nil {
+		return nil, envErr
+	}
+
+	return kConf, nil
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.toml")
+	err := os.WriteFile(path, []byte("token = \"file_token\"\nlog_file = \"bot.log\"\n[ai]\nmax_tok = 15\n"), 0600)
+	require.NoError(t, err)
+
+	tokenPath := filepath.Join(dir, "token")
+	err = os.WriteFile(tokenPath, []byte("secret_token\n"), 0600)
+	require.NoError(t, err)
+
+	t.Setenv("TEST_TOKEN_FILE", tokenPath)
+	t.Setenv("TEST_AI__MAX_TOK", "30")
+	t.Setenv("TEST_LOG_FILE", "other.log")
+	t.Setenv("TEST_SKIPPED_KEY", "skipped")
+
+	kConf, err := Load(path, "TEST_", []string{"token"}, "TEST_SKIPPED_")
+	require.NoError(t, err)
+	require.Equal(t, "secret_token", kConf.String("token"))
+	require.Equal(t, 30, kConf.Int("ai/max_tok"))
+	// only secrets are read from files
+	require.Equal(t, "other.log", kConf.String("log_file"))
+	require.False(t, kConf.Exists("skipped_key"))
+
+	t.Setenv("TEST_TOKEN_FILE", filepath.Join(dir, "missing"))
+	_, err = Load(path, "TEST_", []string{"token"})
+	require.Error(t, err)
+
+	_, err = Load(filepath.Join(dir, "missing.toml"), "TEST_", nil)
+	require.Error(t, err)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/tmc/langchaingo/llms/mistral"
 	"github.com/tmc/langchaingo/llms/openai"
 	"go.uber.org/zap"
+	"sync"
 	"time"
 )
 
@@ -75,8 +76,9 @@ func (chat *aiChat) restart(word string) {
 
 type AI struct {
 	llm     llms.Model
+	mu      sync.RWMutex
 	prompts map[string]string
-	chats   imcache.Cache[int64, *aiChat]
+	chats   imcache.Cache[ChatKey, *aiChat]
 	opts    []llms.CallOption
 	log     *zap.SugaredLogger
 	maxHst  int
@@ -145,62 +147,76 @@ func NewAI(cfg AiConfig, exp time.Duration) (*AI, bool) {
 }
 
 func (ai *AI) SetPrompt(langID, text string) {
+	ai.mu.Lock()
+	defer ai.mu.Unlock()
+
 	ai.prompts[langID] = text
 }
 
-func (ai *AI) PrepareChat(userID int64, langID string) bool {
+// SetPrompts replaces all prompts. Started chats keep their prompts.
+func (ai *AI) SetPrompts(prompts map[string]string) {
+	ai.mu.Lock()
+	defer ai.mu.Unlock()
+
+	ai.prompts = prompts
+}
+
+func (ai *AI) PrepareChat(key ChatKey, langID string) bool {
+	ai.mu.RLock()
 	pmt, ok := ai.prompts[langID]
+	ai.mu.RUnlock()
 	if !ok {
 		return false
 	}
 
 	chat := newAiChat(pmt, ai.maxHst, ai.log)
-	ai.chats.Set(userID, chat, ai.chatExp)
+	ai.chats.Set(key, chat, ai.chatExp)
 	return true
 }
 
-func (ai *AI) RestartChat(userID int64, word string) bool {
-	chat, ok := ai.chats.Get(userID)
+func (ai *AI) RestartChat(key ChatKey, word string) bool {
+	chat, ok := ai.chats.Get(key)
 	if !ok {
-		ai.log.Warnw("chat does not exist", "user_id", userID)
+		ai.log.Warnw("chat does not exist", "chat_id", key.ChatID, "thread_id", key.ThreadID)
 		return false
 	}
 
 	chat.restart(word)
-	ai.log.Infow("chat started", "user_id", userID)
+	ai.log.Infow("chat started", "chat_id", key.ChatID, "thread_id", key.ThreadID)
 	return true
 }
 
-func (ai *AI) StopChat(userID int64) {
-	ai.chats.Remove(userID)
-	ai.log.Infow("chat stopped", "user_id", userID)
+func (ai *AI) StopChat(key ChatKey) {
+	ai.chats.Remove(key)
+	ai.log.Infow("chat stopped", "chat_id", key.ChatID, "thread_id", key.ThreadID)
 }
 
-func (ai *AI) SendMessage(userID int64, text string) (string, bool) {
+func (ai *AI) SendMessage(key ChatKey, text string) (string, bool) {
 	beginTime := time.Now().UnixNano()
 	ai.log.Infow("user message",
-		"user_id", userID,
+		"chat_id", key.ChatID,
+		"thread_id", key.ThreadID,
 		"size", len(text))
 	if len(text) > ai.maxInp {
-		ai.log.Warnw("message from user is too long", "user_id", userID)
+		ai.log.Warnw("message from user is too long", "chat_id", key.ChatID, "thread_id", key.ThreadID)
 		return "", false
 	}
 
-	chat, ok := ai.chats.Get(userID)
+	chat, ok := ai.chats.Get(key)
 	if !ok {
-		ai.log.Warnw("chat for user does not exist", "user_id", userID)
+		ai.log.Warnw("chat for user does not exist", "chat_id", key.ChatID, "thread_id", key.ThreadID)
 		return "", false
 	}
 
 	chat.addUserMessage(text)
 	resp, err := ai.llm.GenerateContent(context.Background(), chat.messages, ai.opts...)
 	if err != nil {
-		ai.log.Warnw(err.Error(), "user_id", userID)
+		ai.log.Warnw(err.Error(), "chat_id", key.ChatID, "thread_id", key.ThreadID)
 		return "", false
 	}
 
 	if len(resp.Choices) == 0 {
-		ai.log.Warnw("no content returned from model", "user_id", userID)
+		ai.log.Warnw("no content returned from model", "chat_id", key.ChatID, "thread_id", key.ThreadID)
 		return "", false
 	}
 
@@ -210,7 +226,7 @@ func (ai *AI) SendMessage(userID int64, text string) (string, bool) {
 
 	reply := resp.Choices[0].Content
 	if reply == "" {
-		ai.log.Warnw("model reply content is empty", "user_id", userID)
+		ai.log.Warnw("model reply content is empty", "chat_id", key.ChatID, "thread_id", key.ThreadID)
 		return "", false
 	}
 
@@ -219,7 +235,8 @@ func (ai *AI) SendMessage(userID int64, text string) (string, bool) {
 	endTime := time.Now().UnixNano()
 	duration := float64(endTime-beginTime) / 1000000
 	ai.log.Infow("ai message",
-		"user_id", userID,
+		"chat_id", key.ChatID,
+		"thread_id", key.ThreadID,
 		"size", len(reply),
 		"dur", fmt.Sprintf("%.2f", duration))
 
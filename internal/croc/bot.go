@@ -8,8 +8,10 @@ import (
 	"golang.org/x/text/language"
 	tele "gopkg.in/telebot.v3"
 	"gopkg.in/telebot.v3/middleware"
+	"html"
 	"math"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -43,8 +45,14 @@ var (
 	msgHelp        = &i18n.Message{ID: "msg_help", Other: "" +
 		"Send /play to start a new game.\n" +
 		"Send /word_pack to select a word pack.\n" +
+		"Send /difficulty to choose how hard the words are.\n" +
+		"Send /taboo to turn taboo mode on or off.\n" +
 		"Send /language to change interface language.\n" +
-		"Send /stop to stop the current game.\n"}
+		"Send /stop to stop the current game.\n" +
+		"Send /daily to play the word of the day against the AI and /daily_top to see the best results.\n" +
+		"Send /profile to see your statistics and achievements.\n" +
+		"Send /rating to see the ratings of the current season.\n" +
+		"Send /addpack to create a word pack for this chat and /packs to manage them.\n"}
 	msgRules = &i18n.Message{ID: "msg_rules", Other: "Hello! " +
 		"I am a bot created to play a word guessing game.\n\n" +
 		"The rules are simple. There is a game host and multiple players. " +
@@ -55,108 +63,107 @@ var (
 		"You can add me to a group and play with friends, " +
 		"or you can play against the AI in single player mode. " +
 		"There are several languages and difficulty levels available."}
-	msgShutdown = &i18n.Message{ID: "msg_shutdown", Other: "The bot is about to update. It usually takes few minutes."}
+	msgShutdown    = &i18n.Message{ID: "msg_shutdown", Other: "The bot is about to update. It usually takes few minutes."}
+	msgTranslation = &i18n.Message{ID: "msg_translation", Other: "Translation: {{.translation}}"}
+	msgSynonyms    = &i18n.Message{ID: "msg_synonyms", Other: "Synonyms: {{.synonyms}}"}
+	msgEtymology   = &i18n.Message{ID: "msg_etymology", Other: "Etymology: {{.etymology}}"}
+	msgDictSource  = &i18n.Message{ID: "msg_dict_source", Other: "Source: {{.source}}"}
 )
 
-type Bot struct {
-	bot  *tele.Bot
-	wdb  *WordDB
-	db   *DB
-	game *Game
-	dict *Dict
-	ai   *AI
-	trs  map[string]*i18n.Localizer
-	log  *zap.SugaredLogger
-
-	packMenus    map[string]*tele.ReplyMarkup
-	langMenu     *tele.ReplyMarkup
-	wordMenus    map[string]*tele.ReplyMarkup
-	wordDefMenus map[string]*tele.ReplyMarkup
-	trMenu       *tele.ReplyMarkup
-
-	startedAt      time.Time
-	userGuessCount atomic.Int64
-	AiGuessCount   atomic.Int64
-	wordCount      atomic.Int64
+// botMessages lists every message the bot sends, so translations can be checked for completeness.
+var botMessages = []*i18n.Message{
+	btnBecomeHost, btnWhatsThat, btnSeeWord, btnPeekDef, btnSkipWord,
+	msgChangeLang, msgLangChanged, msgNewWord, msgCurrPack, msgCurrLang, msgSelectPack, msgAiDisclaim,
+	msgNewHost, msgNotHost, msgGameStopped, msgGameActive, msgYourWord, msgGuessedWord,
+	msgHelp, msgRules, msgShutdown, msgTranslation, msgSynonyms, msgEtymology, msgDictSource,
+	btnDeletePack, msgPackUsage, msgPackNotAdmin, msgPackFewWords, msgPackManyWords, msgPackLongWord,
+	msgPackLongName, msgPackBadWord, msgPackLimit, msgPackFileSize, msgPackSaved, msgPackList, msgNoPacks,
+	msgPackDeleted,
+	btnReport, btnReportObscure, btnReportMisspelled, btnReportOffensive, btnReportDefinition, btnCancel,
+	msgReportSent,
+	btnDifficultyAny, btnDifficultyEasy, btnDifficultyMedium, btnDifficultyHard, btnDifficultyAuto,
+	msgCurrDifficulty, msgDifficultyChanged,
+	msgTabooWords, msgTabooOn, msgTabooOff, msgTabooViolation,
+	msgGuessThrottled, msgGuessLines,
+	msgDailyPrivate, msgDailyNoWord, msgDailyPlayed, msgDailyStarted, msgDailyNoSkip, msgDailySolved, msgDailyShare,
+	msgDailyTop, msgDailyNoResults,
+	msgProfile, msgProfileSeconds, msgProfileAiTurns, msgProfileAchievements, msgProfileNoAchieve, msgPlayer,
+	msgAchievementUnlocked, achFirstGuess, achGuessed100, achQuickGuess, achC2Word, achFirstHost, achHosted100,
+	achAiFirstTurn, achStreak10,
+	msgRating, msgRatingArchive, msgRatingNoSeason, msgRatingChat, msgRatingGlobal, msgRatingGuessers, msgRatingHosts,
+	msgRatingEmpty,
 }
 
-func NewBot(cfg Config, wdb *WordDB, db *DB, game *Game, dict *Dict, ai *AI) (*Bot, bool) {
-	pref := tele.Settings{
-		Token:  cfg.TgToken,
-		Poller: &tele.LongPoller{Timeout: 30 * time.Second},
-	}
-
-	bot := &Bot{
-		wdb:  wdb,
-		db:   db,
-		game: game,
-		dict: dict,
-		ai:   ai,
-		trs:  make(map[string]*i18n.Localizer),
-		log:  zap.L().Named("bot").Sugar(),
-
-		packMenus:    make(map[string]*tele.ReplyMarkup),
-		langMenu:     &tele.ReplyMarkup{},
-		wordMenus:    make(map[string]*tele.ReplyMarkup),
-		wordDefMenus: make(map[string]*tele.ReplyMarkup),
-		trMenu:       &tele.ReplyMarkup{},
+// botState holds the translations and menus rebuilt on every config reload.
+type botState struct {
+	trs             map[string]*i18n.Localizer
+	admins          map[int64]bool
+	packMenus       map[string]map[string]*tele.ReplyMarkup
+	langMenu        *tele.ReplyMarkup
+	wordMenus       map[string]*tele.ReplyMarkup
+	wordDefMenus    map[string]*tele.ReplyMarkup
+	reportMenus     map[string]*tele.ReplyMarkup
+	trMenu          *tele.ReplyMarkup
+	difficultyMenus map[string]*tele.ReplyMarkup
+	log             *zap.SugaredLogger
+}
 
-		startedAt: time.Now(),
+func newBotState(cfg Config, wdb *WordDB, log *zap.SugaredLogger) (*botState, bool) {
+	st := &botState{
+		trs:             make(map[string]*i18n.Localizer),
+		admins:          make(map[int64]bool),
+		packMenus:       make(map[string]map[string]*tele.ReplyMarkup),
+		langMenu:        &tele.ReplyMarkup{},
+		wordMenus:       make(map[string]*tele.ReplyMarkup),
+		wordDefMenus:    make(map[string]*tele.ReplyMarkup),
+		reportMenus:     make(map[string]*tele.ReplyMarkup),
+		trMenu:          &tele.ReplyMarkup{},
+		difficultyMenus: make(map[string]*tele.ReplyMarkup),
+		log:             log,
 	}
 
-	b, err := tele.NewBot(pref)
-	if err != nil {
-		bot.log.Error(err)
-		return nil, false
+	for _, id := range cfg.Admins {
+		st.admins[id] = true
 	}
-	bot.bot = b
 
 	trRows := make([]tele.Row, 0, len(cfg.Translations))
 	for _, tr := range cfg.Translations {
-		tag, err := language.Parse(tr.Locale)
-		if err != nil {
-			bot.log.Error(err)
-			return nil, false
-		}
-
-		bundle := i18n.NewBundle(tag)
-		bundle.RegisterUnmarshalFunc("toml", toml.Unmarshal)
-		_, err = bundle.LoadMessageFile(tr.Path)
+		locale, _, err := loadTranslation(tr)
 		if err != nil {
-			bot.log.Error(err)
+			log.Error(err)
 			return nil, false
 		}
 
-		locale := i18n.NewLocalizer(bundle)
-		bot.trs[tr.Locale] = locale
+		st.trs[tr.Locale] = locale
 
-		btn := bot.trMenu.Data(tr.Name, fmt.Sprintf("tr_%s", tr.Locale), tr.Locale)
-		trRows = append(trRows, bot.trMenu.Row(btn))
-		bot.bot.Handle(&btn, bot.changeTr)
+		btn := st.trMenu.Data(tr.Name, "tr", tr.Locale)
+		trRows = append(trRows, st.trMenu.Row(btn))
 	}
-	bot.trMenu.Inline(trRows...)
-
-	langIDs := bot.wdb.GetLanguageIDs()
-	for _, langID := range langIDs {
-		packIDs, ok := bot.wdb.GetWordPackIDs(langID)
-		if !ok {
-			return nil, false
-		}
+	st.trMenu.Inline(trRows...)
 
-		packRows := make([]tele.Row, 0, len(packIDs))
-		packMenu := &tele.ReplyMarkup{}
-		for _, packID := range packIDs {
-			name, ok := bot.wdb.GetWordPackName(langID, packID)
+	langIDs := wdb.GetLanguageIDs()
+	for _, tr := range cfg.Translations {
+		st.packMenus[tr.Locale] = make(map[string]*tele.ReplyMarkup)
+		for _, langID := range langIDs {
+			packIDs, ok := wdb.GetWordPackIDs(langID)
 			if !ok {
 				return nil, false
 			}
 
-			btn := packMenu.Data(name, fmt.Sprintf("%s_%s", langID, packID), langID, packID)
-			packRows = append(packRows, packMenu.Row(btn))
-			bot.bot.Handle(&btn, bot.changeWordPack)
+			packRows := make([]tele.Row, 0, len(packIDs))
+			packMenu := &tele.ReplyMarkup{}
+			for _, packID := range packIDs {
+				name, ok := wdb.GetLocalWordPackName(langID, packID, tr.Locale)
+				if !ok {
+					return nil, false
+				}
+
+				btn := packMenu.Data(name, "word_pack", langID, packID)
+				packRows = append(packRows, packMenu.Row(btn))
+			}
+			packMenu.Inline(packRows...)
+			st.packMenus[tr.Locale][langID] = packMenu
 		}
-		packMenu.Inline(packRows...)
-		bot.packMenus[langID] = packMenu
 	}
 
 	langRows := make([]tele.Row, 0, len(langIDs))
@@ -166,29 +173,164 @@ func NewBot(cfg Config, wdb *WordDB, db *DB, game *Game, dict *Dict, ai *AI) (*B
 			return nil, false
 		}
 
-		btn := bot.langMenu.Data(langName, fmt.Sprintf("lang_%s", langID), langID)
-		langRows = append(langRows, bot.langMenu.Row(btn))
-		bot.bot.Handle(&btn, bot.showWordPackMenu)
+		btn := st.langMenu.Data(langName, "lang", langID)
+		langRows = append(langRows, st.langMenu.Row(btn))
 	}
-	bot.langMenu.Inline(langRows...)
+	st.langMenu.Inline(langRows...)
 
 	for _, tr := range cfg.Translations {
 		wordMenu := &tele.ReplyMarkup{}
-		seeBtn := wordMenu.Data(bot.tr(btnSeeWord, tr.Locale), "see_word")
-		defBtn := wordMenu.Data(bot.tr(btnPeekDef, tr.Locale), "see_def")
-		skipBtn := wordMenu.Data(bot.tr(btnSkipWord, tr.Locale), "skip_word")
-		wordMenu.Inline(wordMenu.Row(seeBtn), wordMenu.Row(skipBtn))
-		bot.wordMenus[tr.Locale] = wordMenu
+		seeBtn := wordMenu.Data(st.tr(btnSeeWord, tr.Locale), "see_word")
+		defBtn := wordMenu.Data(st.tr(btnPeekDef, tr.Locale), "see_def")
+		skipBtn := wordMenu.Data(st.tr(btnSkipWord, tr.Locale), "skip_word")
+		reportBtn := wordMenu.Data(st.tr(btnReport, tr.Locale), "report_menu")
+		wordMenu.Inline(wordMenu.Row(seeBtn), wordMenu.Row(skipBtn), wordMenu.Row(reportBtn))
+		st.wordMenus[tr.Locale] = wordMenu
 
 		wordDefMenu := &tele.ReplyMarkup{}
-		wordDefMenu.Inline(wordDefMenu.Row(seeBtn), wordDefMenu.Row(defBtn), wordDefMenu.Row(skipBtn))
-		bot.wordDefMenus[tr.Locale] = wordDefMenu
+		wordDefMenu.Inline(wordDefMenu.Row(seeBtn), wordDefMenu.Row(defBtn), wordDefMenu.Row(skipBtn),
+			wordDefMenu.Row(reportBtn))
+		st.wordDefMenus[tr.Locale] = wordDefMenu
+
+		st.reportMenus[tr.Locale] = newReportMenu(st, tr.Locale)
+		st.difficultyMenus[tr.Locale] = newDifficultyMenu(st, tr.Locale)
+	}
+
+	if _, ok := st.trs[cfg.DefaultCfg.Locale]; !ok {
+		log.Errorw("default locale not found", "locale", cfg.DefaultCfg.Locale)
+		return nil, false
+	}
+
+	if _, ok := st.packMenus[cfg.DefaultCfg.Locale][cfg.DefaultCfg.LangID]; !ok {
+		log.Errorw("default language not found", "lang_id", cfg.DefaultCfg.LangID)
+		return nil, false
+	}
 
+	if _, ok := wdb.GetWordPack(cfg.DefaultCfg.LangID, cfg.DefaultCfg.PackID); !ok {
+		log.Errorw("default word pack not found", "pack_id", cfg.DefaultCfg.PackID)
+		return nil, false
+	}
+
+	return st, true
+}
+
+func (st *botState) tr(msg *i18n.Message, locale string) string {
+	return st.trCfg(&i18n.LocalizeConfig{DefaultMessage: msg}, locale)
+}
+
+func (st *botState) trCfg(lc *i18n.LocalizeConfig, locale string) string {
+	msg, err := st.trs[locale].Localize(lc)
+	if err != nil {
+		st.log.Warn(err)
+		return lc.DefaultMessage.Other
+	}
+
+	return msg
+}
+
+type Bot struct {
+	bot     *tele.Bot
+	wdb     *WordDB
+	db      *DB
+	game    *Game
+	packs   *ChatPacks
+	dict    *Dict
+	ai      *AI
+	daily   *Daily
+	ratings *Ratings
+	flood   *FloodGuard
+	state   atomic.Pointer[botState]
+	cfgPath string
+	mu      sync.Mutex
+	log     *zap.SugaredLogger
+
+	startedAt      time.Time
+	userGuessCount atomic.Int64
+	AiGuessCount   atomic.Int64
+	wordCount      atomic.Int64
+	throttledCount atomic.Int64
+}
+
+func NewBot(cfgPath string, cfg Config, wdb *WordDB, db *DB, game *Game, packs *ChatPacks, dict *Dict, ai *AI,
+	daily *Daily, ratings *Ratings) (*Bot, bool) {
+	pref := tele.Settings{
+		Token:  cfg.TgToken,
+		Poller: &tele.LongPoller{Timeout: 30 * time.Second},
+	}
+
+	bot := &Bot{
+		wdb:     wdb,
+		db:      db,
+		game:    game,
+		packs:   packs,
+		dict:    dict,
+		ai:      ai,
+		daily:   daily,
+		ratings: ratings,
+		flood:   NewFloodGuard(cfg.Flood),
+		cfgPath: cfgPath,
+		log:     zap.L().Named("bot").Sugar(),
+
+		startedAt: time.Now(),
+	}
+
+	b, err := tele.NewBot(pref)
+	if err != nil {
+		bot.log.Error(err)
+		return nil, false
+	}
+	bot.bot = b
+
+	st, ok := newBotState(cfg, wdb, bot.log)
+	if !ok {
+		return nil, false
+	}
+	bot.state.Store(st)
+
+	{
+		menu := &tele.ReplyMarkup{}
+		trBtn := menu.Data("", "tr")
+		bot.bot.Handle(&trBtn, bot.changeTr)
+		langBtn := menu.Data("", "lang")
+		bot.bot.Handle(&langBtn, bot.showWordPackMenu)
+		packBtn := menu.Data("", "word_pack")
+		bot.bot.Handle(&packBtn, bot.changeWordPack)
+		difficultyBtn := menu.Data("", "difficulty")
+		bot.bot.Handle(&difficultyBtn, bot.changeDifficulty)
+	}
+
+	{
+		wordMenu := &tele.ReplyMarkup{}
+		seeBtn := wordMenu.Data("", "see_word")
 		bot.bot.Handle(&seeBtn, bot.showWord)
+		defBtn := wordMenu.Data("", "see_def")
 		bot.bot.Handle(&defBtn, bot.showDefinition)
+		skipBtn := wordMenu.Data("", "skip_word")
 		bot.bot.Handle(&skipBtn, bot.skipWord)
 	}
 
+	{
+		reportMenu := &tele.ReplyMarkup{}
+		menuBtn := reportMenu.Data("", "report_menu")
+		bot.bot.Handle(&menuBtn, bot.showReportMenu)
+		wordBtn := reportMenu.Data("", "report_word")
+		bot.bot.Handle(&wordBtn, bot.reportWord)
+		cancelBtn := reportMenu.Data("", "report_cancel")
+		bot.bot.Handle(&cancelBtn, bot.cancelReport)
+		defBtn := reportMenu.Data("", "report_def")
+		bot.bot.Handle(&defBtn, bot.reportDefinition)
+		reviewBtn := reportMenu.Data("", "review_report")
+		bot.bot.Handle(&reviewBtn, bot.reviewReport)
+	}
+
+	{
+		packMenu := &tele.ReplyMarkup{}
+		exportBtn := packMenu.Data("", "export_pack")
+		bot.bot.Handle(&exportBtn, bot.exportChatPack)
+		deleteBtn := packMenu.Data("", "delete_pack")
+		bot.bot.Handle(&deleteBtn, bot.deleteChatPack)
+	}
+
 	{
 		hostMenu := &tele.ReplyMarkup{}
 		hostBtn := hostMenu.Data("", "become_host")
@@ -197,38 +339,202 @@ func NewBot(cfg Config, wdb *WordDB, db *DB, game *Game, dict *Dict, ai *AI) (*B
 		bot.bot.Handle(&whatBtn, bot.showOldDefinition)
 	}
 
-	if _, ok := bot.trs[cfg.DefaultCfg.Locale]; !ok {
-		bot.log.Errorw("default locale not found", "locale", cfg.DefaultCfg.Locale)
-		return nil, false
-	}
-
-	if _, ok := bot.packMenus[cfg.DefaultCfg.LangID]; !ok {
-		bot.log.Errorw("default language not found", "lang_id", cfg.DefaultCfg.LangID)
-		return nil, false
-	}
-
-	if _, ok := bot.wdb.GetWordPack(cfg.DefaultCfg.LangID, cfg.DefaultCfg.PackID); !ok {
-		bot.log.Errorw("default word pack not found", "pack_id", cfg.DefaultCfg.PackID)
-		return nil, false
-	}
+	bot.game.OnAchievements(bot.announceAchievements)
 
 	bot.bot.Use(middleware.Recover())
 	bot.bot.Use(bot.logMessage)
+	bot.bot.Use(bot.useTopics)
 
 	bot.bot.Handle("/start", bot.showTrMenu)
 	bot.bot.Handle("/language", bot.showTrMenu)
 	bot.bot.Handle(tele.OnAddedToGroup, bot.showTrMenu)
 	bot.bot.Handle("/help", bot.showHelp)
 	bot.bot.Handle("/play", bot.playNewGame)
+	bot.bot.Handle("/daily", bot.playDaily)
+	bot.bot.Handle("/daily_top", bot.showDailyTop)
+	bot.bot.Handle("/profile", bot.showProfile)
+	bot.bot.Handle("/rating", bot.showRating)
 	bot.bot.Handle("/stat", bot.getBotStat)
+	bot.bot.Handle("/reload", bot.reloadConfig)
+	bot.bot.Handle("/reports", bot.showReports)
 
 	bot.bot.Handle("/word_pack", bot.showLangMenu)
+	bot.bot.Handle("/difficulty", bot.showDifficultyMenu)
+	bot.bot.Handle("/taboo", bot.toggleTaboo)
+	bot.bot.Handle("/addpack", bot.addChatPack)
+	bot.bot.Handle(tele.OnDocument, bot.addChatPack)
+	bot.bot.Handle("/packs", bot.showChatPacks)
 	bot.bot.Handle("/stop", bot.stopGame)
 	bot.bot.Handle(tele.OnText, bot.checkGuess)
+	bot.bot.Handle(tele.OnCallback, bot.handleLegacyCallback)
 
 	return bot, true
 }
 
+// maxCallbackData is the size limit of the callback data of inline buttons in bytes.
+const maxCallbackData = 64
+
+// fitsCallback reports whether the callback data of the button with the unique and the payload fits the limit.
+func fitsCallback(unique string, data ...string) bool {
+	return len("\f"+unique+"|"+strings.Join(data, "|")) <= maxCallbackData
+}
+
+// whatsThatData returns the callback data of the button showing the definition of the word from the pack.
+// Chat packs have no definitions, so they are looked up only in the dictionary.
+func whatsThatData(pack *WordPack, word string) ([]string, bool) {
+	if pack == nil {
+		return nil, false
+	}
+
+	data := []string{pack.langID, pack.part, word}
+	if !isChatPackID(pack.packID) && fitsCallback("whats_that", append(data, pack.packID)...) {
+		data = append(data, pack.packID)
+	}
+
+	return data, fitsCallback("whats_that", data...)
+}
+
+// legacyCallback converts the data of buttons sent by older versions, which used locales, language
+// and word pack IDs in callback uniques, into the current unique and payload.
+func legacyCallback(data string) (string, string, bool) {
+	data, ok := strings.CutPrefix(data, "\f")
+	if !ok {
+		return "", "", false
+	}
+
+	unique, payload, ok := strings.Cut(data, "|")
+	if !ok {
+		return "", "", false
+	}
+
+	args := strings.Split(payload, "|")
+	switch {
+	case unique == "tr_"+payload:
+		return "tr", payload, true
+	case unique == "lang_"+payload:
+		return "lang", payload, true
+	case len(args) == 2 && unique == args[0]+"_"+args[1]:
+		return "word_pack", payload, true
+	}
+
+	return "", "", false
+}
+
+// handleLegacyCallback routes buttons of menus sent by older versions to their current handlers.
+func (bot *Bot) handleLegacyCallback(c tele.Context) error {
+	cb := c.Callback()
+	if cb == nil {
+		return nil
+	}
+
+	unique, payload, ok := legacyCallback(cb.Data)
+	if !ok {
+		return c.Respond()
+	}
+	cb.Unique, cb.Data = unique, payload
+
+	switch unique {
+	case "tr":
+		return bot.changeTr(c)
+	case "lang":
+		return bot.showWordPackMenu(c)
+	default:
+		return bot.changeWordPack(c)
+	}
+}
+
+// Reload re-reads the config and replaces word packs, AI prompts, translations, menus and dictionaries.
+// Games in progress keep the word packs they started with until they are stopped or expire.
+func (bot *Bot) Reload() bool {
+	bot.mu.Lock()
+	defer bot.mu.Unlock()
+
+	cfg, err := LoadConfig(bot.cfgPath)
+	if err != nil {
+		bot.log.Error(err)
+		return false
+	}
+
+	wdb, ok := LoadWordDB(cfg.Languages)
+	if !ok {
+		return false
+	}
+
+	st, ok := newBotState(cfg, wdb, bot.log)
+	if !ok {
+		return false
+	}
+
+	// nothing is replaced unless the dictionaries can be opened too
+	files, ok := bot.dict.load(cfg)
+	if !ok {
+		return false
+	}
+
+	prompts := make(map[string]string)
+	for _, lang := range cfg.Languages {
+		if lang.Prompt != "" {
+			prompts[lang.ID] = lang.Prompt
+		}
+	}
+
+	bot.wdb.Replace(wdb)
+	bot.game.ResetDifficulty()
+	bot.ai.SetPrompts(prompts)
+	bot.flood.SetConfig(cfg.Flood)
+	bot.daily.SetConfig(cfg)
+	bot.ratings.SetConfig(cfg)
+	bot.state.Store(st)
+	bot.dict.replace(cfg, files)
+
+	bot.log.Infow("config reloaded",
+		"path", bot.cfgPath,
+		"languages", len(wdb.GetLanguageIDs()),
+		"translations", len(st.trs))
+
+	return true
+}
+
+// ReloadDict reopens the dictionaries of the current config. It doesn't run together with Reload.
+func (bot *Bot) ReloadDict() bool {
+	bot.mu.Lock()
+	defer bot.mu.Unlock()
+
+	return bot.dict.Reload(bot.dict.config())
+}
+
+func (bot *Bot) isAdmin(userID int64) bool {
+	return bot.state.Load().admins[userID]
+}
+
+func (bot *Bot) reloadConfig(c tele.Context) error {
+	if !bot.isAdmin(c.Sender().ID) {
+		return nil
+	}
+
+	if !bot.Reload() {
+		return c.Reply("Config reload failed, see the log for details.")
+	}
+
+	return c.Reply("Config reloaded.")
+}
+
+func loadTranslation(tr TranslationConfig) (*i18n.Localizer, *i18n.MessageFile, error) {
+	tag, err := language.Parse(tr.Locale)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bundle := i18n.NewBundle(tag)
+	bundle.RegisterUnmarshalFunc("toml", toml.Unmarshal)
+	file, err := bundle.LoadMessageFile(tr.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return i18n.NewLocalizer(bundle), file, nil
+}
+
 func (bot *Bot) Start() {
 	go func() {
 		bot.log.Info("starting bot")
@@ -238,11 +544,11 @@ func (bot *Bot) Start() {
 }
 
 func (bot *Bot) Stop() {
-	chatIDs := bot.game.GetActiveGames()
-	bot.log.Infow("stopping bot", "games", len(chatIDs))
-	for _, chatID := range chatIDs {
-		msg := bot.tr(msgShutdown, bot.getLocaleByChatID(chatID))
-		_, err := bot.bot.Send(tele.ChatID(chatID), msg)
+	keys := bot.game.GetActiveGames()
+	bot.log.Infow("stopping bot", "games", len(keys))
+	for _, key := range keys {
+		msg := bot.tr(msgShutdown, bot.getLocaleByKey(key))
+		_, err := bot.bot.Send(tele.ChatID(key.ChatID), msg, &tele.SendOptions{ThreadID: key.ThreadID})
 		if err != nil {
 			bot.log.Warn(err)
 		}
@@ -269,6 +575,7 @@ func (bot *Bot) logMessage(next tele.HandlerFunc) tele.HandlerFunc {
 			}
 			bot.log.Infow("user message",
 				"chat_id", c.Chat().ID,
+				"thread_id", getChatKey(c).ThreadID,
 				"chat_type", c.Chat().Type,
 				"user_id", c.Sender().ID,
 				"user_name", c.Sender().Username,
@@ -283,37 +590,89 @@ func (bot *Bot) logMessage(next tele.HandlerFunc) tele.HandlerFunc {
 	}
 }
 
+// getChatKey returns the key of the chat, or of the forum topic the update comes from.
+// Replies in supergroups without topics have thread IDs too, so only topic messages are keyed by threads.
+func getChatKey(c tele.Context) ChatKey {
+	key := ChatKey{ChatID: c.Chat().ID}
+	if msg := c.Message(); msg != nil && msg.TopicMessage {
+		key.ThreadID = msg.ThreadID
+	}
+
+	return key
+}
+
+// topicContext sends messages to the forum topic of the update instead of the General topic.
+type topicContext struct {
+	tele.Context
+	threadID int
+}
+
+func (c *topicContext) Send(what any, opts ...any) error {
+	return c.Context.Send(what, withThread(c.threadID, opts)...)
+}
+
+func (c *topicContext) SendAlbum(a tele.Album, opts ...any) error {
+	return c.Context.SendAlbum(a, withThread(c.threadID, opts)...)
+}
+
+// withThread returns send options with the thread ID set. The options passed as SendOptions
+// replace the preceding ones, so the thread ID is set in them instead of being prepended.
+func withThread(threadID int, opts []any) []any {
+	res := make([]any, 0, len(opts)+1)
+	res = append(res, &tele.SendOptions{ThreadID: threadID})
+	for _, opt := range opts {
+		if so, ok := opt.(*tele.SendOptions); ok && so != nil {
+			withID := *so
+			withID.ThreadID = threadID
+			opt = &withID
+		}
+		res = append(res, opt)
+	}
+
+	return res
+}
+
+// useTopics makes handlers reply to the forum topic the update comes from.
+func (bot *Bot) useTopics(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		if c.Chat() == nil {
+			return next(c)
+		}
+
+		key := getChatKey(c)
+		if key.ThreadID == 0 {
+			return next(c)
+		}
+
+		return next(&topicContext{Context: c, threadID: key.ThreadID})
+	}
+}
+
 func (bot *Bot) getLocale(c tele.Context) string {
-	return bot.getLocaleByChatID(c.Chat().ID)
+	return bot.getLocaleByKey(getChatKey(c))
 }
 
-func (bot *Bot) getLocaleByChatID(chatID int64) string {
-	return bot.db.LoadChatConfig(chatID).Locale
+func (bot *Bot) getLocaleByKey(key ChatKey) string {
+	return bot.db.LoadChatConfig(key).Locale
 }
 
 func (bot *Bot) tr(msg *i18n.Message, locale string) string {
-	return bot.trCfg(&i18n.LocalizeConfig{DefaultMessage: msg}, locale)
+	return bot.state.Load().tr(msg, locale)
 }
 
 func (bot *Bot) trCfg(lc *i18n.LocalizeConfig, locale string) string {
-	msg, err := bot.trs[locale].Localize(lc)
-	if err != nil {
-		bot.log.Warn(err)
-		return lc.DefaultMessage.Other
-	}
-
-	return msg
+	return bot.state.Load().trCfg(lc, locale)
 }
 
 func (bot *Bot) showTrMenu(c tele.Context) error {
 	msg := "Select language."
 
-	return c.Send(msg, bot.trMenu)
+	return c.Send(msg, bot.state.Load().trMenu)
 }
 
 func (bot *Bot) changeTr(c tele.Context) error {
 	locale := c.Data()
-	bot.db.SetLocale(c.Chat().ID, locale)
+	bot.db.SetLocale(getChatKey(c), locale)
 
 	err := c.Respond(&tele.CallbackResponse{Text: bot.tr(msgLangChanged, locale)})
 	if err != nil {
@@ -339,8 +698,12 @@ func (bot *Bot) showHelp(c tele.Context) error {
 
 func (bot *Bot) changeWordPack(c tele.Context) error {
 	langPack := c.Args()
+	if _, _, ok := bot.game.GetDaily(getChatKey(c)); ok {
+		return respondAlert(c, bot.tr(msgGameActive, bot.getLocale(c)))
+	}
+
 	if c.Chat().Type == tele.ChatPrivate {
-		ok := bot.ai.PrepareChat(c.Chat().ID, langPack[0])
+		ok := bot.ai.PrepareChat(getChatKey(c), langPack[0])
 		if !ok {
 			err := respondAlert(c, bot.tr(msgChangeLang, bot.getLocale(c)))
 			if err != nil {
@@ -351,13 +714,13 @@ func (bot *Bot) changeWordPack(c tele.Context) error {
 		}
 	}
 
-	word, hasDef, ok := bot.game.SetWordPack(c.Chat().ID, c.Sender().ID, langPack[0], langPack[1])
+	word, hasDef, ok := bot.game.SetWordPack(getChatKey(c), c.Sender().ID, langPack[0], langPack[1])
 	if !ok {
 		return c.Respond()
 	}
 
 	if word != "" && c.Chat().Type == tele.ChatPrivate {
-		bot.ai.RestartChat(c.Chat().ID, word)
+		bot.ai.RestartChat(getChatKey(c), word)
 	}
 
 	locale := bot.getLocale(c)
@@ -368,28 +731,20 @@ func (bot *Bot) changeWordPack(c tele.Context) error {
 				"word": word,
 			},
 		}
-		err := respondAlert(c, bot.trCfg(lc, locale))
+		err := respondAlert(c, bot.addTabooWords(c, bot.trCfg(lc, locale), locale))
 		if err != nil {
 			bot.log.Warn(err)
 		}
 	}
 
-	bot.db.SetWordPack(c.Chat().ID, langPack[0], langPack[1])
-	langName, _ := bot.wdb.GetLanguageName(langPack[0])
-	packName, _ := bot.wdb.GetWordPackName(langPack[0], langPack[1])
-	lc := &i18n.LocalizeConfig{
-		DefaultMessage: msgCurrPack,
-		TemplateData: map[string]string{
-			"lang": langName,
-			"pack": packName,
-		},
-	}
+	bot.db.SetWordPack(getChatKey(c), langPack[0], langPack[1])
+	msg := bot.getPackMessage(c.Chat().ID, langPack[0], langPack[1], locale)
 	if word == "" {
-		return c.Edit(bot.trCfg(lc, locale), tele.ModeHTML)
+		return c.Edit(msg, tele.ModeHTML)
 	} else if hasDef {
-		return c.Edit(bot.trCfg(lc, locale), bot.wordDefMenus[locale], tele.ModeHTML)
+		return c.Edit(msg, bot.state.Load().wordDefMenus[locale], tele.ModeHTML)
 	} else {
-		return c.Edit(bot.trCfg(lc, locale), bot.wordMenus[locale], tele.ModeHTML)
+		return c.Edit(msg, bot.state.Load().wordMenus[locale], tele.ModeHTML)
 	}
 }
 
@@ -402,26 +757,46 @@ func (bot *Bot) showWordPackMenu(c tele.Context) error {
 			"lang": langName,
 		},
 	}
-	return c.Edit(bot.trCfg(lc, bot.getLocale(c)), bot.packMenus[langID], tele.ModeHTML)
+	locale := bot.getLocale(c)
+	menu := bot.addChatPackButtons(c.Chat().ID, langID, bot.state.Load().packMenus[locale][langID])
+	return c.Edit(bot.trCfg(lc, locale), menu, tele.ModeHTML)
+}
+
+func (bot *Bot) getPackMessage(chatID int64, langID, packID, locale string) string {
+	langName, _ := bot.wdb.GetLanguageName(langID)
+	var packName string
+	if isChatPackID(packID) {
+		pack, ok := bot.packs.GetPack(chatID, packID)
+		if ok {
+			packName = html.EscapeString(pack.Name)
+		}
+	} else {
+		packName, _ = bot.wdb.GetLocalWordPackName(langID, packID, locale)
+	}
+	lc := &i18n.LocalizeConfig{
+		DefaultMessage: msgCurrPack,
+		TemplateData: map[string]string{
+			"lang": langName,
+			"pack": packName,
+		},
+	}
+	msg := bot.trCfg(lc, locale)
+
+	pack, ok := bot.wdb.GetWordPack(langID, packID)
+	if ok && pack.GetDescription(locale) != "" {
+		msg += "\n\n" + html.EscapeString(pack.GetDescription(locale))
+	}
+
+	return msg
 }
 
 func (bot *Bot) getLangMessage(c tele.Context) string {
-	id := c.Chat().ID
-	conf := bot.db.LoadChatConfig(id)
+	conf := bot.db.LoadChatConfig(getChatKey(c))
 	var msg string
 	if conf.PackID == "" {
 		msg = bot.tr(msgSelectPack, bot.getLocale(c))
 	} else {
-		langName, _ := bot.wdb.GetLanguageName(conf.LangID)
-		packName, _ := bot.wdb.GetWordPackName(conf.LangID, conf.PackID)
-		lc := &i18n.LocalizeConfig{
-			DefaultMessage: msgCurrPack,
-			TemplateData: map[string]string{
-				"lang": langName,
-				"pack": packName,
-			},
-		}
-		msg = bot.trCfg(lc, bot.getLocale(c))
+		msg = bot.getPackMessage(c.Chat().ID, conf.LangID, conf.PackID, bot.getLocale(c))
 	}
 
 	return msg
@@ -430,7 +805,7 @@ func (bot *Bot) getLangMessage(c tele.Context) string {
 func (bot *Bot) showLangMenu(c tele.Context) error {
 	msg := bot.getLangMessage(c)
 
-	return c.Send(msg, bot.langMenu, tele.ModeHTML)
+	return c.Send(msg, bot.state.Load().langMenu, tele.ModeHTML)
 }
 
 func (bot *Bot) playNewGame(c tele.Context) error {
@@ -441,24 +816,25 @@ func (bot *Bot) playNewGame(c tele.Context) error {
 	}
 
 	if c.Chat().Type == tele.ChatPrivate {
-		cfg := bot.db.LoadChatConfig(c.Chat().ID)
-		started := bot.ai.PrepareChat(c.Chat().ID, cfg.LangID)
+		cfg := bot.db.LoadChatConfig(getChatKey(c))
+		started := bot.ai.PrepareChat(getChatKey(c), cfg.LangID)
 		if !started {
 			return c.Send(bot.tr(msgChangeLang, bot.getLocale(c)))
 		}
 	}
 
 	locale := bot.getLocale(c)
-	word, hasDef, ok := bot.game.Play(c.Chat().ID, c.Sender().ID)
+	word, hasDef, ok := bot.game.Play(getChatKey(c), c.Sender().ID)
 	if !ok {
 		msg := bot.tr(msgGameActive, locale)
 		return c.Send(msg)
 	}
+	bot.savePlayerName(c.Sender())
 
 	var msg string
 	if c.Chat().Type == tele.ChatPrivate {
 		if word != "" {
-			bot.ai.RestartChat(c.Chat().ID, word)
+			bot.ai.RestartChat(getChatKey(c), word)
 		}
 
 		msg = bot.tr(msgAiDisclaim, locale)
@@ -473,17 +849,17 @@ func (bot *Bot) playNewGame(c tele.Context) error {
 	}
 
 	if hasDef {
-		return c.Send(msg, bot.wordDefMenus[locale], tele.ModeHTML)
+		return c.Send(msg, bot.state.Load().wordDefMenus[locale], tele.ModeHTML)
 	}
-	return c.Send(msg, bot.wordMenus[locale], tele.ModeHTML)
+	return c.Send(msg, bot.state.Load().wordMenus[locale], tele.ModeHTML)
 }
 
 func (bot *Bot) stopGame(c tele.Context) error {
 	if c.Chat().Type == tele.ChatPrivate {
-		bot.ai.StopChat(c.Chat().ID)
+		bot.ai.StopChat(getChatKey(c))
 	}
 
-	ok := bot.game.Stop(c.Chat().ID, c.Sender().ID)
+	ok := bot.game.Stop(getChatKey(c), c.Sender().ID)
 	if !ok {
 		return c.Send(bot.tr(msgNotHost, bot.getLocale(c)))
 	}
@@ -492,15 +868,16 @@ func (bot *Bot) stopGame(c tele.Context) error {
 }
 
 func (bot *Bot) assignGameHost(c tele.Context) error {
-	cfg := bot.db.LoadChatConfig(c.Chat().ID)
-	word, hasDef, ok := bot.game.Play(c.Chat().ID, c.Sender().ID)
+	cfg := bot.db.LoadChatConfig(getChatKey(c))
+	word, hasDef, ok := bot.game.Play(getChatKey(c), c.Sender().ID)
 	if !ok {
 		return respondAlert(c, bot.tr(msgGameActive, cfg.Locale))
 	}
+	bot.savePlayerName(c.Sender())
 
 	if c.Chat().Type == tele.ChatPrivate {
-		bot.ai.PrepareChat(c.Chat().ID, cfg.LangID)
-		bot.ai.RestartChat(c.Chat().ID, word)
+		bot.ai.PrepareChat(getChatKey(c), cfg.LangID)
+		bot.ai.RestartChat(getChatKey(c), word)
 	}
 
 	lc := &i18n.LocalizeConfig{
@@ -509,7 +886,7 @@ func (bot *Bot) assignGameHost(c tele.Context) error {
 			"word": word,
 		},
 	}
-	err := respondAlert(c, bot.trCfg(lc, cfg.Locale))
+	err := respondAlert(c, bot.addTabooWords(c, bot.trCfg(lc, cfg.Locale), cfg.Locale))
 	if err != nil {
 		return err
 	}
@@ -532,14 +909,14 @@ func (bot *Bot) assignGameHost(c tele.Context) error {
 	}
 	msg := bot.trCfg(lc, cfg.Locale)
 	if hasDef {
-		return c.Send(msg, bot.wordDefMenus[cfg.Locale], tele.ModeHTML)
+		return c.Send(msg, bot.state.Load().wordDefMenus[cfg.Locale], tele.ModeHTML)
 	}
-	return c.Send(msg, bot.wordMenus[cfg.Locale], tele.ModeHTML)
+	return c.Send(msg, bot.state.Load().wordMenus[cfg.Locale], tele.ModeHTML)
 }
 
 func (bot *Bot) showWord(c tele.Context) error {
 	var text string
-	word, ok := bot.game.GetWord(c.Chat().ID, c.Sender().ID)
+	word, ok := bot.game.GetWord(getChatKey(c), c.Sender().ID)
 	if ok {
 		lc := &i18n.LocalizeConfig{
 			DefaultMessage: msgYourWord,
@@ -547,7 +924,8 @@ func (bot *Bot) showWord(c tele.Context) error {
 				"word": word,
 			},
 		}
-		text = bot.trCfg(lc, bot.getLocale(c))
+		locale := bot.getLocale(c)
+		text = bot.addTabooWords(c, bot.trCfg(lc, locale), locale)
 	} else {
 		text = bot.tr(msgNotHost, bot.getLocale(c))
 	}
@@ -589,7 +967,7 @@ func truncateDefinition(text string, maxLen int) string {
 }
 
 func (bot *Bot) showDefinition(c tele.Context) error {
-	text, hasDef := bot.game.GetDefinition(c.Chat().ID, c.Sender().ID)
+	text, hasDef := bot.game.GetDefinition(getChatKey(c), c.Sender().ID)
 	if hasDef {
 		text = truncateDefinition(text, 200)
 	} else {
@@ -599,20 +977,135 @@ func (bot *Bot) showDefinition(c tele.Context) error {
 	return respondAlert(c, text)
 }
 
+const (
+	maxCardSenses   = 5
+	maxCardGloss    = 300
+	maxCardExample  = 200
+	maxCardPlainDef = 1000
+)
+
+// renderDictEntry returns the HTML card of the dictionary entry. The card starts with the word line.
+func (bot *Bot) renderDictEntry(word string, entry *DictEntry, locale string) string {
+	var card strings.Builder
+	card.WriteString("<b>" + html.EscapeString(word) + "</b>\n\n")
+
+	if entry.Version == 0 {
+		card.WriteString(html.EscapeString(truncateDefinition(entry.Text(), maxCardPlainDef)))
+		return card.String()
+	}
+
+	synonyms := func(words []string) string {
+		lc := &i18n.LocalizeConfig{
+			DefaultMessage: msgSynonyms,
+			TemplateData: map[string]string{
+				"synonyms": html.EscapeString(strings.Join(words, ", ")),
+			},
+		}
+		return bot.trCfg(lc, locale)
+	}
+
+	senses := entry.Senses
+	if len(senses) > maxCardSenses {
+		senses = senses[:maxCardSenses]
+	}
+	for i, sense := range senses {
+		if len(entry.Senses) > 1 {
+			card.WriteString(fmt.Sprintf("%d) ", i+1))
+		}
+		card.WriteString(html.EscapeString(truncateDefinition(sense.Gloss, maxCardGloss)) + "\n")
+
+		for _, example := range sense.Examples {
+			card.WriteString("<i>" + html.EscapeString(truncateDefinition(example, maxCardExample)) + "</i>\n")
+		}
+
+		if len(sense.Synonyms) > 0 {
+			card.WriteString(synonyms(sense.Synonyms) + "\n")
+		}
+	}
+
+	if len(entry.Synonyms) > 0 {
+		card.WriteString("\n" + synonyms(entry.Synonyms) + "\n")
+	}
+
+	if entry.Etymology != "" {
+		lc := &i18n.LocalizeConfig{
+			DefaultMessage: msgEtymology,
+			TemplateData: map[string]string{
+				"etymology": html.EscapeString(entry.Etymology),
+			},
+		}
+		card.WriteString("\n" + bot.trCfg(lc, locale))
+	}
+
+	return strings.TrimRight(card.String(), "\n")
+}
+
+// getTranslation returns the paragraph with translations of the word into the interface language, if any.
+func (bot *Bot) getTranslation(langID, part, word, locale string) string {
+	target, _, _ := strings.Cut(locale, "-")
+	if target == langID {
+		return ""
+	}
+
+	tr, ok := bot.dict.FindTranslation(langID, part, word, target)
+	if !ok {
+		return ""
+	}
+
+	lc := &i18n.LocalizeConfig{
+		DefaultMessage: msgTranslation,
+		TemplateData: map[string]string{
+			"translation": html.EscapeString(tr),
+		},
+	}
+
+	return "\n\n" + bot.trCfg(lc, locale)
+}
+
 func (bot *Bot) showOldDefinition(c tele.Context) error {
 	langPartWord := c.Args()
 	if len(langPartWord) < 3 {
 		return c.Respond()
 	}
 
-	def, ok := bot.dict.FindDefinition(langPartWord[0], langPartWord[1], langPartWord[2])
-	if !ok {
-		return c.Respond()
+	var entry *DictEntry
+	if len(langPartWord) > 3 {
+		pack, ok := bot.wdb.GetWordPack(langPartWord[0], langPartWord[3])
+		if ok {
+			word, _ := pack.FindWord(langPartWord[2])
+			if word.Definition != "" {
+				entry = ParseDictEntry([]byte(word.Definition))
+			}
+		}
 	}
 
-	def = truncateDefinition(def, 1000)
-	def = langPartWord[2] + "\n\n" + def
-	err := c.Send(def)
+	if entry == nil {
+		var ok bool
+		entry, ok = bot.dict.FindEntry(langPartWord[0], langPartWord[1], langPartWord[2])
+		if !ok {
+			return c.Respond()
+		}
+	}
+
+	var packID string
+	if len(langPartWord) > 3 {
+		packID = langPartWord[3]
+	}
+
+	locale := bot.getLocale(c)
+	def := bot.renderDictEntry(langPartWord[2], entry, locale)
+	def += bot.getTranslation(langPartWord[0], langPartWord[1], langPartWord[2], locale)
+	if entry.Source != "" {
+		lc := &i18n.LocalizeConfig{
+			DefaultMessage: msgDictSource,
+			TemplateData: map[string]string{
+				"source": html.EscapeString(entry.Source),
+			},
+		}
+		def += "\n\n<i>" + bot.trCfg(lc, locale) + "</i>"
+	}
+	reportMenu := bot.newReportDefMenu(locale, langPartWord[0], langPartWord[1], packID)
+	err := c.Send(def, reportMenu, tele.ModeHTML)
 	if err != nil {
 		return err
 	}
@@ -632,7 +1125,11 @@ func (bot *Bot) showOldDefinition(c tele.Context) error {
 
 func (bot *Bot) skipWord(c tele.Context) error {
 	locale := bot.getLocale(c)
-	word, hasDef, ok := bot.game.SkipWord(c.Chat().ID, c.Sender().ID)
+	if _, _, ok := bot.game.GetDaily(getChatKey(c)); ok {
+		return respondAlert(c, bot.tr(msgDailyNoSkip, locale))
+	}
+
+	word, hasDef, ok := bot.game.SkipWord(getChatKey(c), c.Sender().ID)
 	if !ok {
 		text := bot.tr(msgNotHost, locale)
 		return respondAlert(c, text)
@@ -644,15 +1141,15 @@ func (bot *Bot) skipWord(c tele.Context) error {
 			"word": word,
 		},
 	}
-	text := bot.trCfg(lc, locale)
+	text := bot.addTabooWords(c, bot.trCfg(lc, locale), locale)
 
-	oldHasDef := len(c.Message().ReplyMarkup.InlineKeyboard) > 2
+	oldHasDef := hasButton(c.Message().ReplyMarkup, "see_def")
 	if oldHasDef != hasDef {
 		var err error
 		if hasDef {
-			err = c.Edit(bot.wordDefMenus[locale], tele.ModeHTML)
+			err = c.Edit(bot.state.Load().wordDefMenus[locale], tele.ModeHTML)
 		} else {
-			err = c.Edit(bot.wordMenus[locale], tele.ModeHTML)
+			err = c.Edit(bot.state.Load().wordMenus[locale], tele.ModeHTML)
 		}
 		if err != nil {
 			return err
@@ -660,19 +1157,30 @@ func (bot *Bot) skipWord(c tele.Context) error {
 	}
 
 	if c.Chat().Type == tele.ChatPrivate {
-		bot.ai.RestartChat(c.Chat().ID, word)
+		bot.ai.RestartChat(getChatKey(c), word)
 	}
 
 	return respondAlert(c, text)
 }
 
 func (bot *Bot) checkGuess(c tele.Context) error {
-	if !bot.game.IsActive(c.Chat().ID) {
+	if c.Sender() == nil || c.Message() == nil || !bot.game.IsActive(getChatKey(c)) {
+		return nil
+	}
+
+	// the host can't dodge the taboo by forwarding or posting via a bot
+	broken, err := bot.checkTaboo(c)
+	if broken || err != nil {
+		return err
+	}
+
+	if isIgnoredGuess(c) {
 		return nil
 	}
 
 	guess := c.Text()
 	guesser := c.Sender()
+	dailyLang, day, isDaily := bot.game.GetDaily(getChatKey(c))
 
 	if c.Chat().Type == tele.ChatPrivate {
 		bot.AiGuessCount.Add(1)
@@ -683,7 +1191,7 @@ func (bot *Bot) checkGuess(c tele.Context) error {
 			replied = "> " + strings.ReplaceAll(replied, "\n", "\n> ")
 			text = replied + "\n\n" + text
 		}
-		reply, ok := bot.ai.SendMessage(c.Chat().ID, text)
+		reply, ok := bot.ai.SendMessage(getChatKey(c), text)
 		if !ok {
 			return nil
 		}
@@ -696,17 +1204,42 @@ func (bot *Bot) checkGuess(c tele.Context) error {
 		guess = reply
 		guesser = bot.bot.Me
 	} else {
+		allowed, err := bot.allowGuess(c)
+		if !allowed || err != nil {
+			return err
+		}
+
 		bot.userGuessCount.Add(1)
 	}
 
-	word, hasDef, guessed := bot.game.CheckGuess(c.Chat().ID, guesser.ID, guess)
+	word, pack, hasDef, guessed := bot.game.CheckGuess(getChatKey(c), guesser.ID, guess)
 	if !guessed {
 		return nil
 	}
 
 	bot.wordCount.Add(1)
+	bot.savePlayerName(c.Sender())
 
 	locale := bot.getLocale(c)
+	hostMenu := &tele.ReplyMarkup{}
+	var whatRow tele.Row
+	if hasDef {
+		data, ok := whatsThatData(pack, word)
+		if ok {
+			whatRow = hostMenu.Row(hostMenu.Data(bot.tr(btnWhatsThat, locale), "whats_that", data...))
+		}
+	}
+
+	if isDaily {
+		// the daily word is not played with a host
+		var menu *tele.ReplyMarkup
+		if whatRow != nil {
+			hostMenu.Inline(whatRow)
+			menu = hostMenu
+		}
+		return bot.sendDailyResult(c, dailyLang, day, menu)
+	}
+
 	lc := &i18n.LocalizeConfig{
 		DefaultMessage: msgGuessedWord,
 		TemplateData: map[string]string{
@@ -716,21 +1249,12 @@ func (bot *Bot) checkGuess(c tele.Context) error {
 	}
 	msg := bot.trCfg(lc, locale)
 
-	hostMenu := &tele.ReplyMarkup{}
 	hostBtn := hostMenu.Data(bot.tr(btnBecomeHost, locale), "become_host")
-	if hasDef {
-		cfg := bot.db.LoadChatConfig(c.Chat().ID)
-		pack, ok := bot.wdb.GetWordPack(cfg.LangID, cfg.PackID)
-		if ok {
-			whatBtn := hostMenu.Data(bot.tr(btnWhatsThat, locale), "whats_that",
-				pack.langID, pack.part, word)
-			hostMenu.Inline(hostMenu.Row(hostBtn), hostMenu.Row(whatBtn))
-		} else {
-			hostMenu.Inline(hostMenu.Row(hostBtn))
-		}
-	} else {
-		hostMenu.Inline(hostMenu.Row(hostBtn))
+	rows := []tele.Row{hostMenu.Row(hostBtn)}
+	if whatRow != nil {
+		rows = append(rows, whatRow)
 	}
+	hostMenu.Inline(rows...)
 
 	return c.Send(msg, hostMenu, tele.ModeHTML)
 }
@@ -762,6 +1286,8 @@ func (bot *Bot) getBotStat(c tele.Context) error {
 	wordCnt := bot.wordCount.Load()
 	addI64("Total words guessed", wordCnt)
 
+	addI64("Throttled guesses", bot.throttledCount.Load())
+
 	if uptimeDays > 0.1 {
 		avgAiGuessCnt := float64(aiGuessCnt) / uptimeDays
 		addF64("AI guesses per day", avgAiGuessCnt)
@@ -1,6 +1,14 @@
 package croc
 
-import "testing"
+import (
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/text/language"
+	tele "gopkg.in/telebot.v3"
+	"testing"
+	"time"
+)
 
 func TestTruncateDefinition(t *testing.T) {
 	tests := []struct {
@@ -24,3 +32,170 @@ func TestTruncateDefinition(t *testing.T) {
 		}
 	}
 }
+
+func TestWithThread(t *testing.T) {
+	markup := &tele.ReplyMarkup{}
+	opts := withThread(5, []any{markup, tele.ModeHTML})
+	require.Len(t, opts, 3)
+	require.Equal(t, 5, opts[0].(*tele.SendOptions).ThreadID)
+
+	replyTo := &tele.Message{ID: 1}
+	sendOpts := &tele.SendOptions{ReplyTo: replyTo}
+	opts = withThread(5, []any{sendOpts})
+	require.Len(t, opts, 2)
+	require.Equal(t, 5, opts[1].(*tele.SendOptions).ThreadID)
+	require.Equal(t, replyTo, opts[1].(*tele.SendOptions).ReplyTo)
+	require.Zero(t, sendOpts.ThreadID)
+}
+
+func TestLegacyCallback(t *testing.T) {
+	tests := []struct {
+		data    string
+		unique  string
+		payload string
+		ok      bool
+	}{
+		{"\ftr_ru|ru", "tr", "ru", true},
+		{"\flang_en|en", "lang", "en", true},
+		{"\fen_A1|en|A1", "word_pack", "en|A1", true},
+		{"\fen_A1|en|B1", "", "", false},
+		{"\fsee_word", "", "", false},
+		{"tr_ru|ru", "", "", false},
+	}
+
+	for _, tt := range tests {
+		unique, payload, ok := legacyCallback(tt.data)
+		require.Equal(t, tt.ok, ok, tt.data)
+		require.Equal(t, tt.unique, unique, tt.data)
+		require.Equal(t, tt.payload, payload, tt.data)
+	}
+}
+
+func TestFitsCallback(t *testing.T) {
+	require.True(t, fitsCallback("whats_that", "en", "noun", "cat", "A1"))
+	// Cyrillic letters take two bytes
+	require.False(t, fitsCallback("whats_that", "ru", "noun", "достопримечательность", "B2"))
+	require.True(t, fitsCallback("whats_that", "ru", "noun", "достопримечательность"))
+}
+
+// testContext records the messages sent in reply to a test message.
+type testContext struct {
+	tele.Context
+	msg  *tele.Message
+	sent []testMessage
+}
+
+type testMessage struct {
+	what any
+	opts []any
+}
+
+func (c *testContext) Message() *tele.Message {
+	return c.msg
+}
+
+func (c *testContext) Sender() *tele.User {
+	return c.msg.Sender
+}
+
+func (c *testContext) Chat() *tele.Chat {
+	return c.msg.Chat
+}
+
+func (c *testContext) Text() string {
+	return c.msg.Text
+}
+
+func (c *testContext) Send(what any, opts ...any) error {
+	c.sent = append(c.sent, testMessage{what: what, opts: opts})
+	return nil
+}
+
+func setupTestBot(t *testing.T) *Bot {
+	db := setupTestDB(t)
+	wdb := setupTestWordDB(t)
+	log := zap.NewNop().Sugar()
+
+	bot := &Bot{
+		wdb:   wdb,
+		db:    db,
+		game:  NewGame(db, wdb, nil, nil, NewDaily(db, wdb, Config{}), NewRatings(db, Config{}), time.Hour),
+		flood: NewFloodGuard(FloodConfig{}),
+		log:   log,
+	}
+	bot.state.Store(&botState{
+		trs: map[string]*i18n.Localizer{"en": i18n.NewLocalizer(i18n.NewBundle(language.English), "en")},
+		log: log,
+	})
+
+	return bot
+}
+
+func TestCheckGuessForwardedTaboo(t *testing.T) {
+	bot := setupTestBot(t)
+	chat := &tele.Chat{ID: -1, Type: tele.ChatGroup}
+	key := ChatKey{ChatID: chat.ID}
+
+	pack, _ := bot.wdb.GetWordPack("en", "pack1")
+	bot.game.games.Set(key, &gameConfig{
+		pack:      pack,
+		word:      Word{Text: "word1"},
+		hostID:    1,
+		startedAt: time.Now(),
+		tabooMode: true,
+		taboo:     []string{"pet"},
+	}, bot.game.exp)
+
+	// forwarded messages of other players are still ignored
+	c := &testContext{msg: &tele.Message{
+		Sender:         &tele.User{ID: 2},
+		Chat:           chat,
+		Text:           "word1",
+		OriginalSender: &tele.User{ID: 3},
+	}}
+	require.NoError(t, bot.checkGuess(c))
+	require.Empty(t, c.sent)
+	require.True(t, bot.game.IsActive(key))
+
+	c = &testContext{msg: &tele.Message{
+		Sender:         &tele.User{ID: 1},
+		Chat:           chat,
+		Text:           "my pets",
+		OriginalSender: &tele.User{ID: 3},
+	}}
+	require.True(t, c.msg.IsForwarded())
+	require.NoError(t, bot.checkGuess(c))
+	require.Len(t, c.sent, 1)
+	require.False(t, bot.game.IsActive(key))
+}
+
+func TestCheckGuessWhatsThat(t *testing.T) {
+	bot := setupTestBot(t)
+	chat := &tele.Chat{ID: -1, Type: tele.ChatGroup}
+	key := ChatKey{ChatID: chat.ID}
+
+	// the chat config still points to the default pack
+	pack := &WordPack{langID: "de", packID: "animals", part: "noun", words: []Word{{Text: "Katze", Definition: "a pet"}}}
+	bot.game.games.Set(key, &gameConfig{
+		pack:      pack,
+		word:      pack.words[0],
+		def:       pack.words[0].Definition,
+		hostID:    1,
+		startedAt: time.Now(),
+	}, bot.game.exp)
+
+	c := &testContext{msg: &tele.Message{Sender: &tele.User{ID: 2}, Chat: chat, Text: "katze"}}
+	require.NoError(t, bot.checkGuess(c))
+	require.Len(t, c.sent, 1)
+
+	menu := c.sent[0].opts[0].(*tele.ReplyMarkup)
+	require.Len(t, menu.InlineKeyboard, 2)
+	require.Equal(t, "whats_that", menu.InlineKeyboard[1][0].Unique)
+	require.Equal(t, "de|noun|Katze|animals", menu.InlineKeyboard[1][0].Data)
+
+	data, ok := whatsThatData(&WordPack{langID: "en", packID: chatPackID(5)}, "cat")
+	require.True(t, ok)
+	require.Equal(t, []string{"en", "", "cat"}, data)
+	_, ok = whatsThatData(nil, "cat")
+	require.False(t, ok)
+}
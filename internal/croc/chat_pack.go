@@ -0,0 +1,430 @@
+package croc
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	btnDeletePack = &i18n.Message{ID: "btn_delete_pack", Other: "Delete"}
+	msgPackUsage  = &i18n.Message{
+		ID: "msg_pack_usage",
+		Other: "Send /addpack with the pack name on the first line and one word per line below it, " +
+			"or attach a text file with the caption \"/addpack name\". " +
+			"If the pack with the same name exists, its words will be replaced.",
+	}
+	msgPackNotAdmin  = &i18n.Message{ID: "msg_pack_not_admin", Other: "Only chat admins can change word packs."}
+	msgPackFewWords  = &i18n.Message{ID: "msg_pack_few_words", Other: "The pack should contain at least {{.count}} words."}
+	msgPackManyWords = &i18n.Message{ID: "msg_pack_many_words", Other: "The pack should contain at most {{.count}} words."}
+	msgPackLongWord  = &i18n.Message{ID: "msg_pack_long_word", Other: "The word \"{{.word}}\" is too long."}
+	msgPackLongName  = &i18n.Message{ID: "msg_pack_long_name", Other: "The pack name is too long."}
+	msgPackBadWord   = &i18n.Message{ID: "msg_pack_bad_word", Other: "The pack contains inappropriate words."}
+	msgPackLimit     = &i18n.Message{
+		ID:    "msg_pack_limit",
+		Other: "This chat already has {{.count}} word packs. Delete one of them first.",
+	}
+	msgPackFileSize = &i18n.Message{ID: "msg_pack_file_size", Other: "The file is too large."}
+	msgPackSaved    = &i18n.Message{
+		ID:    "msg_pack_saved",
+		Other: "Word pack <b>{{.name}}</b> with {{.count}} words is saved. Send /word_pack to select it.",
+	}
+	msgPackList    = &i18n.Message{ID: "msg_pack_list", Other: "Word packs of this chat. Tap a pack to download it."}
+	msgNoPacks     = &i18n.Message{ID: "msg_no_packs", Other: "This chat has no word packs yet. Send /addpack to create one."}
+	msgPackDeleted = &i18n.Message{ID: "msg_pack_deleted", Other: "Word pack deleted."}
+)
+
+const chatPackPrefix = "chat_"
+
+func isChatPackID(packID string) bool {
+	return strings.HasPrefix(packID, chatPackPrefix)
+}
+
+func chatPackID(id uint) string {
+	return chatPackPrefix + strconv.FormatUint(uint64(id), 10)
+}
+
+func parseChatPackID(packID string) (uint, bool) {
+	id, err := strconv.ParseUint(strings.TrimPrefix(packID, chatPackPrefix), 10, 32)
+	return uint(id), err == nil && isChatPackID(packID)
+}
+
+// ChatPacks manages word packs uploaded by chat admins.
+type ChatPacks struct {
+	db     *DB
+	cfg    ChatPackConfig
+	banned map[string]bool
+	log    *zap.SugaredLogger
+}
+
+func NewChatPacks(db *DB, cfg ChatPackConfig) (*ChatPacks, bool) {
+	if cfg.MaxPacks <= 0 {
+		cfg.MaxPacks = 10
+	}
+	if cfg.MinWords <= 0 {
+		cfg.MinWords = 10
+	}
+	if cfg.MaxWords <= 0 {
+		cfg.MaxWords = 1000
+	}
+	if cfg.MaxWordLen <= 0 {
+		cfg.MaxWordLen = 30
+	}
+	if cfg.MaxNameLen <= 0 {
+		cfg.MaxNameLen = 30
+	}
+	if cfg.MaxFileSize <= 0 {
+		cfg.MaxFileSize = 64 * 1024
+	}
+
+	cp := &ChatPacks{
+		db:     db,
+		cfg:    cfg,
+		banned: make(map[string]bool),
+		log:    zap.L().Named("chat_packs").Sugar(),
+	}
+
+	if cfg.BannedPath != "" {
+		words, err := ReadWords(cfg.BannedPath)
+		if err != nil {
+			cp.log.Error(err)
+			return nil, false
+		}
+
+		for _, word := range words {
+			cp.banned[strings.ToLower(word)] = true
+		}
+	}
+
+	return cp, true
+}
+
+func (cp *ChatPacks) isBanned(text string) bool {
+	tokens := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '\''
+	})
+	for _, token := range tokens {
+		if cp.banned[token] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validate cleans up words and checks pack limits. It returns the message for the user on error.
+func (cp *ChatPacks) validate(name string, words []string) ([]string, *i18n.LocalizeConfig) {
+	if name == "" {
+		return nil, &i18n.LocalizeConfig{DefaultMessage: msgPackUsage}
+	}
+
+	if utf8.RuneCountInString(name) > cp.cfg.MaxNameLen {
+		return nil, &i18n.LocalizeConfig{DefaultMessage: msgPackLongName}
+	}
+
+	if cp.isBanned(name) {
+		return nil, &i18n.LocalizeConfig{DefaultMessage: msgPackBadWord}
+	}
+
+	unique := make(map[string]bool, len(words))
+	result := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		if word == "" || unique[strings.ToLower(word)] {
+			continue
+		}
+		unique[strings.ToLower(word)] = true
+
+		if utf8.RuneCountInString(word) > cp.cfg.MaxWordLen {
+			return nil, &i18n.LocalizeConfig{
+				DefaultMessage: msgPackLongWord,
+				TemplateData:   map[string]string{"word": truncateDefinition(word, cp.cfg.MaxWordLen)},
+			}
+		}
+
+		if cp.isBanned(word) {
+			return nil, &i18n.LocalizeConfig{DefaultMessage: msgPackBadWord}
+		}
+
+		result = append(result, word)
+	}
+
+	if len(result) < cp.cfg.MinWords {
+		return nil, &i18n.LocalizeConfig{
+			DefaultMessage: msgPackFewWords,
+			TemplateData:   map[string]string{"count": strconv.Itoa(cp.cfg.MinWords)},
+		}
+	}
+
+	if len(result) > cp.cfg.MaxWords {
+		return nil, &i18n.LocalizeConfig{
+			DefaultMessage: msgPackManyWords,
+			TemplateData:   map[string]string{"count": strconv.Itoa(cp.cfg.MaxWords)},
+		}
+	}
+
+	return result, nil
+}
+
+// Save validates and stores the pack. It returns the message for the user on error.
+func (cp *ChatPacks) Save(chatID int64, langID, name string, words []string) (*ChatPack, *i18n.LocalizeConfig) {
+	words, lc := cp.validate(name, words)
+	if lc != nil {
+		return nil, lc
+	}
+
+	exists := false
+	for _, pack := range cp.db.LoadChatPacks(chatID, langID) {
+		exists = exists || pack.Name == name
+	}
+
+	if !exists && cp.db.CountChatPacks(chatID) >= int64(cp.cfg.MaxPacks) {
+		return nil, &i18n.LocalizeConfig{
+			DefaultMessage: msgPackLimit,
+			TemplateData:   map[string]string{"count": strconv.Itoa(cp.cfg.MaxPacks)},
+		}
+	}
+
+	pack := &ChatPack{
+		ChatID: chatID,
+		LangID: langID,
+		Name:   name,
+		Words:  strings.Join(words, "\n"),
+	}
+	cp.db.SaveChatPack(pack)
+
+	cp.log.Infow("chat pack saved",
+		"chat_id", chatID,
+		"lang_id", langID,
+		"pack_id", pack.ID,
+		"words", len(words))
+
+	return pack, nil
+}
+
+func (cp *ChatPacks) GetWordPack(chatID int64, packID string) (*WordPack, bool) {
+	pack, ok := cp.GetPack(chatID, packID)
+	if !ok {
+		return nil, false
+	}
+
+	words := strings.Split(pack.Words, "\n")
+	wp := &WordPack{
+		langID: pack.LangID,
+		packID: packID,
+		words:  make([]Word, 0, len(words)),
+	}
+	for _, word := range words {
+		wp.words = append(wp.words, Word{Text: word})
+	}
+
+	return wp, true
+}
+
+func (cp *ChatPacks) GetPack(chatID int64, packID string) (*ChatPack, bool) {
+	id, ok := parseChatPackID(packID)
+	if !ok {
+		return nil, false
+	}
+
+	pack, ok := cp.db.LoadChatPack(chatID, id)
+	if !ok {
+		cp.log.Warnw("chat pack doesn't exist",
+			"chat_id", chatID,
+			"pack_id", packID)
+		return nil, false
+	}
+
+	return pack, true
+}
+
+func (cp *ChatPacks) GetPacks(chatID int64, langID string) []ChatPack {
+	return cp.db.LoadChatPacks(chatID, langID)
+}
+
+func (cp *ChatPacks) Delete(chatID int64, packID string) bool {
+	id, ok := parseChatPackID(packID)
+	if !ok {
+		return false
+	}
+
+	if !cp.db.DeleteChatPack(chatID, id) {
+		return false
+	}
+
+	// topics of the forum playing the pack switch to the default one
+	reset := cp.db.ResetChatPack(chatID, packID)
+
+	cp.log.Infow("chat pack deleted",
+		"chat_id", chatID,
+		"pack_id", packID,
+		"reset", reset)
+
+	return true
+}
+
+func (bot *Bot) isChatAdmin(c tele.Context) bool {
+	if c.Chat().Type == tele.ChatPrivate {
+		return true
+	}
+
+	member, err := bot.bot.ChatMemberOf(c.Chat(), c.Sender())
+	if err != nil {
+		bot.log.Warn(err)
+		return false
+	}
+
+	return member.Role == tele.Creator || member.Role == tele.Administrator
+}
+
+func (bot *Bot) addChatPack(c tele.Context) error {
+	if !strings.HasPrefix(c.Text(), "/addpack") {
+		return nil
+	}
+
+	locale := bot.getLocale(c)
+	if !bot.isChatAdmin(c) {
+		return c.Reply(bot.tr(msgPackNotAdmin, locale))
+	}
+
+	lines := strings.Split(c.Text(), "\n")
+	var name string
+	if cmd := strings.SplitN(strings.TrimSpace(lines[0]), " ", 2); len(cmd) > 1 {
+		name = strings.TrimSpace(cmd[1])
+	}
+	words := lines[1:]
+
+	if doc := c.Message().Document; doc != nil {
+		if doc.FileSize > bot.packs.cfg.MaxFileSize {
+			return c.Reply(bot.tr(msgPackFileSize, locale))
+		}
+
+		file, err := bot.bot.File(&doc.File)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = file.Close() }()
+
+		words = words[:0]
+		scanner := bufio.NewScanner(io.LimitReader(file, bot.packs.cfg.MaxFileSize))
+		for scanner.Scan() {
+			words = append(words, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	cfg := bot.db.LoadChatConfig(getChatKey(c))
+	pack, lc := bot.packs.Save(c.Chat().ID, cfg.LangID, name, words)
+	if lc != nil {
+		return c.Reply(bot.trCfg(lc, locale), tele.ModeHTML)
+	}
+
+	lc = &i18n.LocalizeConfig{
+		DefaultMessage: msgPackSaved,
+		TemplateData: map[string]string{
+			"name":  html.EscapeString(pack.Name),
+			"count": strconv.Itoa(strings.Count(pack.Words, "\n") + 1),
+		},
+	}
+	return c.Reply(bot.trCfg(lc, locale), tele.ModeHTML)
+}
+
+func (bot *Bot) getChatPacksMenu(chatID int64, locale string) (*tele.ReplyMarkup, bool) {
+	packs := bot.packs.GetPacks(chatID, "")
+	if len(packs) == 0 {
+		return nil, false
+	}
+
+	menu := &tele.ReplyMarkup{}
+	rows := make([]tele.Row, 0, len(packs))
+	for _, pack := range packs {
+		langName, _ := bot.wdb.GetLanguageName(pack.LangID)
+		name := fmt.Sprintf("%s (%s)", pack.Name, langName)
+		exportBtn := menu.Data(name, "export_pack", chatPackID(pack.ID))
+		deleteBtn := menu.Data(bot.tr(btnDeletePack, locale), "delete_pack", chatPackID(pack.ID))
+		rows = append(rows, menu.Row(exportBtn, deleteBtn))
+	}
+	menu.Inline(rows...)
+
+	return menu, true
+}
+
+func (bot *Bot) showChatPacks(c tele.Context) error {
+	locale := bot.getLocale(c)
+	menu, ok := bot.getChatPacksMenu(c.Chat().ID, locale)
+	if !ok {
+		return c.Send(bot.tr(msgNoPacks, locale))
+	}
+
+	return c.Send(bot.tr(msgPackList, locale), menu)
+}
+
+func (bot *Bot) exportChatPack(c tele.Context) error {
+	pack, ok := bot.packs.GetPack(c.Chat().ID, c.Data())
+	if !ok {
+		return c.Respond()
+	}
+
+	doc := &tele.Document{
+		File:     tele.FromReader(strings.NewReader(pack.Words + "\n")),
+		FileName: pack.Name + ".txt",
+		MIME:     "text/plain",
+	}
+	err := c.Send(doc)
+	if err != nil {
+		return err
+	}
+
+	return c.Respond()
+}
+
+func (bot *Bot) deleteChatPack(c tele.Context) error {
+	locale := bot.getLocale(c)
+	if !bot.isChatAdmin(c) {
+		return respondAlert(c, bot.tr(msgPackNotAdmin, locale))
+	}
+
+	if !bot.packs.Delete(c.Chat().ID, c.Data()) {
+		return c.Respond()
+	}
+
+	err := respondAlert(c, bot.tr(msgPackDeleted, locale))
+	if err != nil {
+		return err
+	}
+
+	menu, ok := bot.getChatPacksMenu(c.Chat().ID, locale)
+	if !ok {
+		return c.Edit(bot.tr(msgNoPacks, locale))
+	}
+
+	return c.Edit(bot.tr(msgPackList, locale), menu)
+}
+
+// addChatPackButtons returns the copy of the word pack menu with the chat packs in the language.
+func (bot *Bot) addChatPackButtons(chatID int64, langID string, menu *tele.ReplyMarkup) *tele.ReplyMarkup {
+	packs := bot.packs.GetPacks(chatID, langID)
+	if len(packs) == 0 {
+		return menu
+	}
+
+	chatMenu := &tele.ReplyMarkup{}
+	rows := make([][]tele.InlineButton, 0, len(menu.InlineKeyboard)+len(packs))
+	rows = append(rows, menu.InlineKeyboard...)
+	for _, pack := range packs {
+		btn := chatMenu.Data(pack.Name, "word_pack", langID, chatPackID(pack.ID))
+		rows = append(rows, []tele.InlineButton{*btn.Inline()})
+	}
+	chatMenu.InlineKeyboard = rows
+
+	return chatMenu
+}
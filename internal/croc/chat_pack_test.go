@@ -0,0 +1,103 @@
+package croc
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func setupTestChatPacks(t *testing.T) *ChatPacks {
+	db := setupTestDB(t)
+
+	path := filepath.Join(t.TempDir(), "banned.txt")
+	err := os.WriteFile(path, []byte("Badword\n"), 0644)
+	require.NoError(t, err)
+
+	packs, ok := NewChatPacks(db, ChatPackConfig{
+		MaxPacks:   2,
+		MinWords:   2,
+		MaxWords:   3,
+		MaxWordLen: 10,
+		MaxNameLen: 10,
+		BannedPath: path,
+	})
+	require.True(t, ok)
+
+	return packs
+}
+
+func TestChatPacksValidate(t *testing.T) {
+	packs := setupTestChatPacks(t)
+
+	tests := []struct {
+		name  string
+		words []string
+		want  []string
+		msg   string
+	}{
+		{name: "pack", words: []string{" cat", "dog", "", "Cat"}, want: []string{"cat", "dog"}},
+		{name: "", words: []string{"cat", "dog"}, msg: msgPackUsage.ID},
+		{name: "a very long name", words: []string{"cat", "dog"}, msg: msgPackLongName.ID},
+		{name: "pack", words: []string{"cat"}, msg: msgPackFewWords.ID},
+		{name: "pack", words: []string{"cat", "dog", "cow", "pig"}, msg: msgPackManyWords.ID},
+		{name: "pack", words: []string{"cat", "hippopotamus"}, msg: msgPackLongWord.ID},
+		{name: "pack", words: []string{"cat", "a badword"}, msg: msgPackBadWord.ID},
+		{name: "badword", words: []string{"cat", "dog"}, msg: msgPackBadWord.ID},
+	}
+
+	for _, tt := range tests {
+		words, lc := packs.validate(tt.name, tt.words)
+		if tt.msg == "" {
+			require.Nil(t, lc)
+			require.Equal(t, tt.want, words)
+		} else {
+			require.NotNil(t, lc)
+			require.Equal(t, tt.msg, lc.DefaultMessage.ID)
+		}
+	}
+}
+
+func TestChatPacksSave(t *testing.T) {
+	packs := setupTestChatPacks(t)
+
+	pack, lc := packs.Save(1, "en", "pack", []string{"cat", "dog"})
+	require.Nil(t, lc)
+	packID := chatPackID(pack.ID)
+
+	wp, ok := packs.GetWordPack(1, packID)
+	require.True(t, ok)
+	require.Equal(t, "en", wp.GetLangID())
+	require.Equal(t, packID, wp.GetPackID())
+	require.Len(t, wp.words, 2)
+
+	_, ok = packs.GetWordPack(2, packID)
+	require.False(t, ok)
+
+	pack, lc = packs.Save(1, "en", "pack", []string{"cow", "pig", "cat"})
+	require.Nil(t, lc)
+	require.Equal(t, packID, chatPackID(pack.ID))
+
+	wp, ok = packs.GetWordPack(1, packID)
+	require.True(t, ok)
+	require.Len(t, wp.words, 3)
+
+	_, lc = packs.Save(1, "en", "pack2", []string{"cow", "pig"})
+	require.Nil(t, lc)
+	_, lc = packs.Save(1, "en", "pack3", []string{"cow", "pig"})
+	require.NotNil(t, lc)
+	require.Equal(t, msgPackLimit.ID, lc.DefaultMessage.ID)
+
+	require.Len(t, packs.GetPacks(1, "en"), 2)
+	require.Len(t, packs.GetPacks(1, "fr"), 0)
+
+	packs.db.SetWordPack(ChatKey{ChatID: 1}, "en", packID)
+	packs.db.SetWordPack(ChatKey{ChatID: 1, ThreadID: 5}, "en", packID)
+	require.False(t, packs.Delete(2, packID))
+	require.True(t, packs.Delete(1, packID))
+	require.Equal(t, defaultChatCfg.PackID, packs.db.LoadChatConfig(ChatKey{ChatID: 1}).PackID)
+	require.Equal(t, defaultChatCfg.PackID, packs.db.LoadChatConfig(ChatKey{ChatID: 1, ThreadID: 5}).PackID)
+
+	_, ok = packs.GetWordPack(1, packID)
+	require.False(t, ok)
+}
@@ -0,0 +1,169 @@
+package croc
+
+import (
+	"crocodiler/internal/config"
+	"fmt"
+	"io"
+)
+
+type configChecker struct {
+	out      io.Writer
+	errors   int
+	warnings int
+}
+
+func (cc *configChecker) errorf(format string, args ...any) {
+	cc.errors++
+	_, _ = fmt.Fprintf(cc.out, "ERROR: "+format+"\n", args...)
+}
+
+func (cc *configChecker) warnf(format string, args ...any) {
+	cc.warnings++
+	_, _ = fmt.Fprintf(cc.out, "WARNING: "+format+"\n", args...)
+}
+
+// CheckConfig loads the config at path, verifies that everything it refers to
+// can be loaded, and writes found problems to out. It returns false on errors.
+func CheckConfig(path string, out io.Writer) bool {
+	cc := &configChecker{out: out}
+	_, _ = fmt.Fprintf(out, "Checking config %s...\n", path)
+
+	cc.check(path)
+
+	_, _ = fmt.Fprintf(out, "Errors: %d. Warnings: %d.\n", cc.errors, cc.warnings)
+
+	return cc.errors == 0
+}
+
+func (cc *configChecker) check(path string) {
+	kConf, err := config.Load(path, config.EnvPrefix, secretKeys, config.HelperEnvPrefix)
+	if err != nil {
+		cc.errorf("can't load config: %s", err)
+		return
+	}
+
+	var cfg Config
+	unused, err := unmarshalConfig(kConf, &cfg)
+	if err != nil {
+		cc.errorf("can't parse config: %s", err)
+		return
+	}
+
+	for _, key := range unused {
+		cc.errorf("unknown key %q", key)
+	}
+
+	err = cfg.validate()
+	if err != nil {
+		cc.errorf("%s", err)
+	}
+
+	if cfg.ChatPacks.BannedPath != "" {
+		_, err = ReadWords(cfg.ChatPacks.BannedPath)
+		if err != nil {
+			cc.errorf("can't load banned words: %s", err)
+		}
+	}
+
+	cc.checkTranslations(cfg)
+	wdb := cc.checkWordPacks(cfg)
+	cc.checkDictionary(cfg, wdb)
+}
+
+func (cc *configChecker) checkTranslations(cfg Config) {
+	hasDefault := false
+	for _, tr := range cfg.Translations {
+		if tr.Locale == cfg.DefaultCfg.Locale {
+			hasDefault = true
+		}
+
+		_, file, err := loadTranslation(tr)
+		if err != nil {
+			cc.errorf("can't load translation %s from %s: %s", tr.Locale, tr.Path, err)
+			continue
+		}
+
+		ids := make(map[string]bool, len(file.Messages))
+		for _, msg := range file.Messages {
+			ids[msg.ID] = true
+		}
+
+		for _, msg := range botMessages {
+			if !ids[msg.ID] {
+				cc.errorf("translation %s has no message %s", tr.Locale, msg.ID)
+			}
+		}
+	}
+
+	if !hasDefault {
+		cc.errorf("default locale %q not found in translations", cfg.DefaultCfg.Locale)
+	}
+}
+
+func (cc *configChecker) checkWordPacks(cfg Config) *WordDB {
+	wdb := NewWordDB()
+	for _, lang := range cfg.Languages {
+		if lang.Prompt == "" {
+			cc.warnf("language %s has no prompt, single player mode is disabled", lang.ID)
+		}
+
+		if len(lang.WordPacks) == 0 {
+			cc.errorf("language %s has no word packs", lang.ID)
+		}
+
+		for _, pack := range lang.WordPacks {
+			ok := wdb.LoadWordPack(pack.Path, lang.ID, pack.ID, pack.Part, lang.Name, pack.Name)
+			if !ok {
+				cc.errorf("can't load word pack %s/%s from %s", lang.ID, pack.ID, pack.Path)
+			}
+
+			if pack.Level < 0 || pack.Level > maxPackLevel {
+				cc.errorf("word pack %s/%s level must be from 0 to %d", lang.ID, pack.ID, maxPackLevel)
+			}
+		}
+
+		if lang.DailyPack != "" {
+			if _, ok := wdb.packs[lang.ID][lang.DailyPack]; !ok {
+				cc.errorf("daily word pack %s/%s not found", lang.ID, lang.DailyPack)
+			} else if lang.Prompt == "" {
+				cc.warnf("language %s has no prompt, its daily challenge can't be played", lang.ID)
+			}
+		}
+	}
+
+	if _, ok := wdb.packs[cfg.DefaultCfg.LangID]; !ok {
+		cc.errorf("default language %q not found", cfg.DefaultCfg.LangID)
+	} else if _, ok := wdb.packs[cfg.DefaultCfg.LangID][cfg.DefaultCfg.PackID]; !ok {
+		cc.errorf("default word pack %q not found", cfg.DefaultCfg.PackID)
+	}
+
+	return wdb
+}
+
+func (cc *configChecker) checkDictionary(cfg Config, wdb *WordDB) {
+	if cfg.DictPath == "" {
+		for _, lang := range cfg.Languages {
+			if len(lang.Dicts) == 0 {
+				cc.errorf("dictionary path is not set and language %s has no dictionaries", lang.ID)
+				return
+			}
+		}
+	}
+
+	dict, ok := LoadDict(cfg)
+	if !ok {
+		cc.errorf("can't open dictionaries")
+		return
+	}
+	defer dict.Close()
+
+	for _, langID := range wdb.GetLanguageIDs() {
+		for _, packID := range wdb.packIDs[langID] {
+			pack := wdb.packs[langID][packID]
+			if !dict.HasBucket(pack.GetLangID(), pack.GetPart()) {
+				cc.errorf("dictionary has no definitions for %s/%s used by word pack %s",
+					pack.GetLangID(), pack.GetPart(), packID)
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package croc
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testCheckConfig = `
+tg_token = "token"
+dict_path = %q
+%s
+
+[default_cfg]
+locale = "en"
+lang_id = "en"
+pack_id = "pack1"
+
+[[translations]]
+locale = "en"
+name = "English"
+path = "../../i18n/active.en.toml"
+
+[[translations]]
+locale = "ru"
+name = "Русский"
+path = "../../i18n/active.ru.toml"
+
+[[languages]]
+id = "en"
+name = "English"
+prompt = "prompt"
+
+[[languages.word_packs]]
+id = "pack1"
+name = "Pack 1"
+path = %q
+part = "noun"
+`
+
+func setupTestCheckConfig(t *testing.T, extra string) string {
+	dir := t.TempDir()
+
+	packPath := filepath.Join(dir, "pack1.txt")
+	err := os.WriteFile(packPath, []byte("apple\n"), 0644)
+	require.NoError(t, err)
+
+	dictPath := filepath.Join(dir, "dict.db")
+	db, err := bolt.Open(dictPath, 0600, nil)
+	require.NoError(t, err)
+	err = db.Update(func(tx *bolt.Tx) error {
+		bkt, err := tx.CreateBucket([]byte("en"))
+		if err != nil {
+			return err
+		}
+		_, err = bkt.CreateBucket([]byte("noun"))
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	path := filepath.Join(dir, "crocodiler.toml")
+	cfg := fmt.Sprintf(testCheckConfig, dictPath, extra, packPath)
+	err = os.WriteFile(path, []byte(cfg), 0644)
+	require.NoError(t, err)
+
+	return path
+}
+
+func TestCheckConfig(t *testing.T) {
+	path := setupTestCheckConfig(t, "")
+
+	var out bytes.Buffer
+	ok := CheckConfig(path, &out)
+	require.True(t, ok, out.String())
+}
+
+func TestCheckConfigUnknownKey(t *testing.T) {
+	path := setupTestCheckConfig(t, `db = "data/db.sqlite"`)
+
+	var out bytes.Buffer
+	ok := CheckConfig(path, &out)
+	require.False(t, ok)
+	require.Contains(t, out.String(), `unknown key "db"`)
+}
@@ -1,21 +1,33 @@
 package croc
 
 import (
+	"crocodiler/internal/config"
 	"errors"
-	"github.com/knadh/koanf/parsers/toml"
-	"github.com/knadh/koanf/providers/file"
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/knadh/koanf/v2"
 	"time"
 )
 
+const DefaultConfigPath = "crocodiler.toml"
+
+// secretKeys can be read from files named by environment variables with the _FILE suffix.
+var secretKeys = []string{"tg_token", "ai/api_key"}
+
 type Config struct {
-	TgToken      string `koanf:"tg_token"`
-	DBPath       string `koanf:"db_path"`
-	DictPath     string `koanf:"dict_path"`
+	TgToken  string `koanf:"tg_token"`
+	DBPath   string `koanf:"db_path"`
+	DictPath string `koanf:"dict_path"`
+	DictName string `koanf:"dict_name"`
+	// DailySeed makes words of the day unpredictable from word packs.
+	DailySeed    string `koanf:"daily_seed"`
 	Release      bool
+	Admins       []int64
 	Ai           AiConfig
-	GameExp      time.Duration `koanf:"game_exp"`
-	DefaultCfg   DefaultConfig `koanf:"default_cfg"`
+	GameExp      time.Duration  `koanf:"game_exp"`
+	DefaultCfg   DefaultConfig  `koanf:"default_cfg"`
+	ChatPacks    ChatPackConfig `koanf:"chat_packs"`
+	Flood        FloodConfig
+	Rating       RatingConfig
 	Translations []TranslationConfig
 	Languages    []LanguageConfig
 }
@@ -38,6 +50,34 @@ type DefaultConfig struct {
 	PackID string `koanf:"pack_id"`
 }
 
+type ChatPackConfig struct {
+	MaxPacks    int    `koanf:"max_packs"`
+	MinWords    int    `koanf:"min_words"`
+	MaxWords    int    `koanf:"max_words"`
+	MaxWordLen  int    `koanf:"max_word_len"`
+	MaxNameLen  int    `koanf:"max_name_len"`
+	MaxFileSize int64  `koanf:"max_file_size"`
+	BannedPath  string `koanf:"banned_path"`
+}
+
+// FloodConfig limits guesses in group chats. Zero values are replaced with defaults.
+type FloodConfig struct {
+	// Window is the period the guesses are counted in.
+	Window      time.Duration
+	UserGuesses int `koanf:"user_guesses"`
+	ChatGuesses int `koanf:"chat_guesses"`
+	// MaxLines is the number of lines of the message above which it is not a guess.
+	MaxLines int `koanf:"max_lines"`
+}
+
+// RatingConfig sets up seasons of player ratings. Zero values are replaced with defaults.
+type RatingConfig struct {
+	// SeasonMonths is the length of seasons, they are counted from January 2024.
+	SeasonMonths int `koanf:"season_months"`
+	// KFactor is the largest rating change in one round.
+	KFactor float64 `koanf:"k_factor"`
+}
+
 type TranslationConfig struct {
 	Locale string
 	Name   string
@@ -45,41 +85,75 @@ type TranslationConfig struct {
 }
 
 type LanguageConfig struct {
-	ID        string
-	Name      string
-	Prompt    string
+	ID     string
+	Name   string
+	Prompt string
+	// Dicts are looked up in order instead of the default dictionary.
+	Dicts     []DictConfig
 	WordPacks []WordPackConfig `koanf:"word_packs"`
+	// DailyPack is the word pack the word of the day is picked from.
+	DailyPack string `koanf:"daily_pack"`
 }
 
-type WordPackConfig struct {
-	ID   string
+type DictConfig struct {
+	// Name is shown under definitions from the dictionary.
 	Name string
 	Path string
-	Part string
 }
 
-func LoadConfig() (Config, error) {
-	var kConf = koanf.New("/")
+type WordPackConfig struct {
+	ID      string
+	Name    string
+	Path    string
+	Part    string
+	Exclude string
+	// Level is the level of the pack words from 1 (A1) to 6 (C2), zero if unknown. Ratings use it
+	// along with the observed difficulty of words.
+	Level int
+}
 
+func LoadConfig(path string) (Config, error) {
 	var cfg Config
 
-	err := kConf.Load(file.Provider("crocodiler.toml"), toml.Parser())
+	kConf, err := config.Load(path, config.EnvPrefix, secretKeys, config.HelperEnvPrefix)
 	if err != nil {
 		return cfg, err
 	}
 
-	err = kConf.Unmarshal("", &cfg)
+	_, err = unmarshalConfig(kConf, &cfg)
 	if err != nil {
 		return cfg, err
 	}
 
+	return cfg, cfg.validate()
+}
+
+// unmarshalConfig decodes the config and returns the keys
+// that don't match any config field.
+func unmarshalConfig(kConf *koanf.Koanf, cfg *Config) ([]string, error) {
+	var md mapstructure.Metadata
+	err := kConf.UnmarshalWithConf("", cfg, koanf.UnmarshalConf{
+		DecoderConfig: &mapstructure.DecoderConfig{
+			DecodeHook: mapstructure.ComposeDecodeHookFunc(
+				mapstructure.StringToTimeDurationHookFunc(),
+				mapstructure.TextUnmarshallerHookFunc()),
+			Metadata:         &md,
+			Result:           cfg,
+			WeaklyTypedInput: true,
+		},
+	})
+
+	return md.Unused, err
+}
+
+func (cfg *Config) validate() error {
 	if cfg.TgToken == "" {
-		return cfg, errors.New("telegram token is required")
+		return errors.New("telegram token is required")
 	}
 
 	if len(cfg.Languages) == 0 {
-		return cfg, errors.New("no word packs provided")
+		return errors.New("no word packs provided")
 	}
 
-	return cfg, nil
+	return nil
 }
@@ -0,0 +1,72 @@
+package croc
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testConfig = `
+tg_token = "file_token"
+db_path = "data/db.sqlite"
+game_exp = "72h"
+
+[ai]
+provider = "openai"
+max_tok = 15
+
+[default_cfg]
+locale = "en"
+lang_id = "en"
+
+[[languages]]
+id = "en"
+name = "English"
+`
+
+func setupTestConfig(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "crocodiler.toml")
+	err := os.WriteFile(path, []byte(testConfig), 0644)
+	require.NoError(t, err)
+
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := setupTestConfig(t)
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, "file_token", cfg.TgToken)
+	require.Equal(t, "openai", cfg.Ai.Provider)
+	require.Equal(t, 15, cfg.Ai.MaxTok)
+	require.Len(t, cfg.Languages, 1)
+
+	_, err = LoadConfig(filepath.Join(t.TempDir(), "missing.toml"))
+	require.Error(t, err)
+}
+
+func TestLoadConfigEnv(t *testing.T) {
+	path := setupTestConfig(t)
+
+	keyPath := filepath.Join(t.TempDir(), "api_key")
+	err := os.WriteFile(keyPath, []byte("secret_key\n"), 0600)
+	require.NoError(t, err)
+
+	t.Setenv("CROC_TG_TOKEN", "env_token")
+	t.Setenv("CROC_AI__MAX_TOK", "30")
+	t.Setenv("CROC_AI__API_KEY_FILE", keyPath)
+	t.Setenv("CROC_HELPER_DICT_PATH", "ignored")
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, "env_token", cfg.TgToken)
+	require.Equal(t, 30, cfg.Ai.MaxTok)
+	require.Equal(t, "secret_key", cfg.Ai.ApiKey)
+	require.Equal(t, "data/db.sqlite", cfg.DBPath)
+
+	t.Setenv("CROC_AI__API_KEY_FILE", filepath.Join(t.TempDir(), "missing"))
+	_, err = LoadConfig(path)
+	require.Error(t, err)
+}
@@ -0,0 +1,286 @@
+package croc
+
+import (
+	"fmt"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+	"hash/fnv"
+	"html"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	msgDailyPrivate = &i18n.Message{
+		ID:    "msg_daily_private",
+		Other: "The daily challenge is played against the AI. Send /daily to me in a private chat.",
+	}
+	msgDailyNoWord = &i18n.Message{ID: "msg_daily_no_word", Other: "There is no daily challenge in this language."}
+	msgDailyPlayed = &i18n.Message{
+		ID:    "msg_daily_played",
+		Other: "You have already played today's challenge. Come back tomorrow!",
+	}
+	msgDailyStarted = &i18n.Message{
+		ID: "msg_daily_started",
+		Other: "Daily challenge #{{.number}}. Everyone gets the same word today. " +
+			"Explain it to the AI in as few messages as you can.",
+	}
+	msgDailyNoSkip = &i18n.Message{
+		ID:    "msg_daily_no_skip",
+		Other: "The daily word can't be skipped. Send /stop to give up.",
+	}
+	msgDailySolved = &i18n.Message{
+		ID:    "msg_daily_solved",
+		Other: "Daily challenge solved! AI turns: {{.turns}}. Streak: {{.streak}}, best: {{.best}}.",
+	}
+	msgDailyShare = &i18n.Message{ID: "msg_daily_share", Other: "Forward the next message to share your result."}
+	msgDailyTop   = &i18n.Message{
+		ID:    "msg_daily_top",
+		Other: "Daily challenge #{{.number}}, <b>{{.lang}}</b>. Played: {{.played}}, solved: {{.solved}}.",
+	}
+	msgDailyNoResults = &i18n.Message{ID: "msg_daily_no_results", Other: "Nobody has solved it yet."}
+)
+
+// dailyEpoch is the day of the first daily challenge, challenges are numbered from it.
+var dailyEpoch = dailyDay(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+// dailyTopSize is the number of players on the daily leaderboard.
+const dailyTopSize = 10
+
+// dailyDay returns the number of the UTC day since the Unix epoch.
+func dailyDay(t time.Time) int {
+	return int(t.Unix() / (24 * 60 * 60))
+}
+
+func dailyNumber(day int) int {
+	return day - dailyEpoch + 1
+}
+
+// Daily picks the word of the day for each language and keeps results of the daily challenges.
+type Daily struct {
+	db    *DB
+	wdb   *WordDB
+	mu    sync.RWMutex
+	seed  string
+	packs map[string]string
+	log   *zap.SugaredLogger
+}
+
+func NewDaily(db *DB, wdb *WordDB, cfg Config) *Daily {
+	d := &Daily{
+		db:  db,
+		wdb: wdb,
+		log: zap.L().Named("daily").Sugar(),
+	}
+	d.SetConfig(cfg)
+
+	return d
+}
+
+// SetConfig replaces the seed and the word packs of daily challenges.
+func (d *Daily) SetConfig(cfg Config) {
+	packs := make(map[string]string)
+	for _, lang := range cfg.Languages {
+		if lang.DailyPack != "" {
+			packs[lang.ID] = lang.DailyPack
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.seed = cfg.DailySeed
+	d.packs = packs
+}
+
+// GetWord returns the word of the day in the language. The word depends only on the seed, the language,
+// the day and the words of the pack, so editing the pack may change the word of the current day.
+func (d *Daily) GetWord(langID string, day int) (Word, *WordPack, bool) {
+	d.mu.RLock()
+	packID, ok := d.packs[langID]
+	seed := d.seed
+	d.mu.RUnlock()
+	if !ok {
+		return Word{}, nil, false
+	}
+
+	pack, ok := d.wdb.GetWordPack(langID, packID)
+	if !ok {
+		d.log.Warnw("daily word pack not found",
+			"lang_id", langID,
+			"pack_id", packID)
+		return Word{}, nil, false
+	}
+
+	hash := fnv.New64a()
+	_, _ = fmt.Fprintf(hash, "%s\t%s\t%d", seed, langID, day)
+
+	return pack.GetSeededWord(hash.Sum64()), pack, true
+}
+
+// Start records the start of the daily challenge. It returns false if the user has played it already.
+func (d *Daily) Start(userID int64, name, langID string, day int) bool {
+	ok := d.db.StartDaily(&DailyResult{
+		Day:    day,
+		LangID: langID,
+		UserID: userID,
+		Name:   name,
+	})
+	if ok {
+		d.log.Infow("daily challenge started",
+			"user_id", userID,
+			"lang_id", langID,
+			"day", day)
+	}
+
+	return ok
+}
+
+// Finish records the outcome of the daily challenge and updates the streak of the user.
+func (d *Daily) Finish(userID int64, langID string, day, turns int, solved bool) {
+	if !d.db.FinishDaily(day, langID, userID, turns, solved) {
+		return
+	}
+
+	st := d.db.UpdateDailyStreak(userID, langID, day, solved)
+
+	d.log.Infow("daily challenge finished",
+		"user_id", userID,
+		"lang_id", langID,
+		"day", day,
+		"turns", turns,
+		"solved", solved,
+		"streak", st.Current)
+}
+
+// dailyShareText returns the result of the challenge without the word, one square per AI turn.
+func dailyShareText(langID string, result *DailyResult, streak int) string {
+	const maxSquares = 10
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("🐊 #%d %s\n", dailyNumber(result.Day), strings.ToUpper(langID)))
+	if !result.Solved {
+		text.WriteString("❌")
+	} else {
+		misses := result.Turns - 1
+		text.WriteString(strings.Repeat("🟥", min(misses, maxSquares-1)))
+		if misses >= maxSquares {
+			text.WriteString("…")
+		}
+		text.WriteString("🟩 ")
+		text.WriteString(strconv.Itoa(result.Turns))
+	}
+	if streak > 1 {
+		text.WriteString(fmt.Sprintf("\n🔥 %d", streak))
+	}
+
+	return text.String()
+}
+
+func (bot *Bot) playDaily(c tele.Context) error {
+	locale := bot.getLocale(c)
+	if c.Chat().Type != tele.ChatPrivate {
+		return c.Send(bot.tr(msgDailyPrivate, locale))
+	}
+
+	key := getChatKey(c)
+	if bot.game.IsActive(key) {
+		return c.Send(bot.tr(msgGameActive, locale))
+	}
+
+	cfg := bot.db.LoadChatConfig(key)
+	day := dailyDay(time.Now())
+	word, pack, ok := bot.daily.GetWord(cfg.LangID, day)
+	if !ok {
+		return c.Send(bot.tr(msgDailyNoWord, locale))
+	}
+
+	if !bot.ai.PrepareChat(key, cfg.LangID) {
+		return c.Send(bot.tr(msgChangeLang, locale))
+	}
+
+	name := strings.TrimSpace(c.Sender().FirstName + " " + c.Sender().LastName)
+	if !bot.daily.Start(c.Sender().ID, name, cfg.LangID, day) {
+		return c.Send(bot.tr(msgDailyPlayed, locale))
+	}
+
+	hasDef, ok := bot.game.PlayDaily(key, c.Sender().ID, word, pack, day)
+	if !ok {
+		return c.Send(bot.tr(msgGameActive, locale))
+	}
+	bot.ai.RestartChat(key, word.Text)
+
+	lc := &i18n.LocalizeConfig{
+		DefaultMessage: msgDailyStarted,
+		TemplateData: map[string]string{
+			"number": strconv.Itoa(dailyNumber(day)),
+		},
+	}
+	msg := bot.trCfg(lc, locale)
+	if hasDef {
+		return c.Send(msg, bot.state.Load().wordDefMenus[locale], tele.ModeHTML)
+	}
+	return c.Send(msg, bot.state.Load().wordMenus[locale], tele.ModeHTML)
+}
+
+// sendDailyResult sends the result of the solved daily challenge with the menu and the text to share.
+func (bot *Bot) sendDailyResult(c tele.Context, langID string, day int, menu *tele.ReplyMarkup) error {
+	result, ok := bot.db.LoadDailyResult(day, langID, c.Sender().ID)
+	if !ok {
+		return nil
+	}
+	st := bot.db.LoadDailyStreak(c.Sender().ID, langID)
+
+	locale := bot.getLocale(c)
+	lc := &i18n.LocalizeConfig{
+		DefaultMessage: msgDailySolved,
+		TemplateData: map[string]string{
+			"turns":  strconv.Itoa(result.Turns),
+			"streak": strconv.Itoa(st.GetCurrent(day)),
+			"best":   strconv.Itoa(st.Best),
+		},
+	}
+	err := c.Send(bot.trCfg(lc, locale)+"\n"+bot.tr(msgDailyShare, locale), menu)
+	if err != nil {
+		return err
+	}
+
+	return c.Send(dailyShareText(langID, result, st.GetCurrent(day)))
+}
+
+func (bot *Bot) showDailyTop(c tele.Context) error {
+	cfg := bot.db.LoadChatConfig(getChatKey(c))
+	day := dailyDay(time.Now())
+	langName, ok := bot.wdb.GetLanguageName(cfg.LangID)
+	if !ok {
+		langName = cfg.LangID
+	}
+
+	played, solved := bot.db.CountDailyResults(day, cfg.LangID)
+	lc := &i18n.LocalizeConfig{
+		DefaultMessage: msgDailyTop,
+		TemplateData: map[string]string{
+			"number": strconv.Itoa(dailyNumber(day)),
+			"lang":   html.EscapeString(langName),
+			"played": strconv.FormatInt(played, 10),
+			"solved": strconv.FormatInt(solved, 10),
+		},
+	}
+
+	var msg strings.Builder
+	msg.WriteString(bot.trCfg(lc, cfg.Locale))
+	msg.WriteString("\n\n")
+
+	results := bot.db.LoadDailyResults(day, cfg.LangID, dailyTopSize)
+	if len(results) == 0 {
+		msg.WriteString(bot.tr(msgDailyNoResults, cfg.Locale))
+	}
+	for i, result := range results {
+		msg.WriteString(fmt.Sprintf("%d. %s — %d\n", i+1, html.EscapeString(result.Name), result.Turns))
+	}
+
+	return c.Send(msg.String(), tele.ModeHTML)
+}
@@ -0,0 +1,83 @@
+package croc
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestDailyWord(t *testing.T) {
+	wdb := setupTestWordDB(t)
+	cfg := Config{
+		DailySeed: "seed",
+		Languages: []LanguageConfig{{ID: "en", DailyPack: "pack1"}},
+	}
+	daily := NewDaily(setupTestDB(t), wdb, cfg)
+
+	_, _, ok := daily.GetWord("fr", 100)
+	require.False(t, ok)
+
+	words := make(map[string]bool)
+	for day := 100; day < 120; day++ {
+		word, pack, ok := daily.GetWord("en", day)
+		require.True(t, ok)
+		require.Equal(t, "pack1", pack.GetPackID())
+
+		again, _, _ := daily.GetWord("en", day)
+		require.Equal(t, word, again)
+		words[word.Text] = true
+	}
+	require.Len(t, words, 2)
+}
+
+func TestDailyResults(t *testing.T) {
+	db := setupTestDB(t)
+	daily := NewDaily(db, NewWordDB(), Config{})
+	day := dailyDay(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+
+	require.True(t, daily.Start(1, "Alice", "en", day))
+	require.False(t, daily.Start(1, "Alice", "en", day))
+	require.True(t, daily.Start(2, "Bob", "en", day))
+	require.True(t, daily.Start(3, "Carol", "en", day))
+	daily.Finish(1, "en", day, 4, true)
+	daily.Finish(2, "en", day, 2, true)
+	daily.Finish(3, "en", day, 5, false)
+	// the finished challenge can't be replayed
+	daily.Finish(1, "en", day, 1, true)
+
+	results := db.LoadDailyResults(day, "en", dailyTopSize)
+	require.Len(t, results, 2)
+	require.Equal(t, "Bob", results[0].Name)
+	require.Equal(t, 4, results[1].Turns)
+	played, solved := db.CountDailyResults(day, "en")
+	require.EqualValues(t, 3, played)
+	require.EqualValues(t, 2, solved)
+
+	require.True(t, daily.Start(1, "Alice", "en", day+1))
+	daily.Finish(1, "en", day+1, 3, true)
+	st := db.LoadDailyStreak(1, "en")
+	require.Equal(t, 2, st.GetCurrent(day+1))
+	require.Equal(t, 2, st.GetCurrent(day+2))
+	require.Equal(t, 0, st.GetCurrent(day+3))
+
+	require.True(t, daily.Start(1, "Alice", "en", day+2))
+	daily.Finish(1, "en", day+2, 3, false)
+	st = db.LoadDailyStreak(1, "en")
+	require.Equal(t, 0, st.GetCurrent(day+2))
+	require.Equal(t, 2, st.Best)
+
+	require.Equal(t, 0, db.LoadDailyStreak(3, "en").Current)
+}
+
+func TestDailyShareText(t *testing.T) {
+	day := dailyEpoch + 9
+
+	text := dailyShareText("en", &DailyResult{Day: day, Turns: 3, Solved: true}, 5)
+	require.Equal(t, "🐊 #10 EN\n🟥🟥🟩 3\n🔥 5", text)
+
+	text = dailyShareText("ru", &DailyResult{Day: day, Turns: 3}, 0)
+	require.Equal(t, "🐊 #10 RU\n❌", text)
+
+	text = dailyShareText("en", &DailyResult{Day: day, Turns: 15, Solved: true}, 1)
+	require.Equal(t, "🐊 #10 EN\n🟥🟥🟥🟥🟥🟥🟥🟥🟥…🟩 15", text)
+}
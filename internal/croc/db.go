@@ -1,9 +1,12 @@
 package croc
 
 import (
+	"fmt"
 	"github.com/glebarez/sqlite"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
 	"time"
 )
 
@@ -12,16 +15,152 @@ type DB struct {
 	cfg ChatConfig
 }
 
+// ChatKey identifies the chat, or the topic of the forum supergroup.
+// The thread ID is zero outside of forum topics.
+type ChatKey struct {
+	ChatID   int64
+	ThreadID int
+}
+
+// IsPrivate reports whether the key is of the private chat with the user.
+func (key ChatKey) IsPrivate() bool {
+	return key.ChatID > 0
+}
+
+// ChatConfig holds settings of the chat, or of the forum topic.
 type ChatConfig struct {
-	ChatID    int64 `gorm:"primaryKey;autoIncrement:false"`
+	ChatID     int64 `gorm:"primaryKey;autoIncrement:false"`
+	ThreadID   int   `gorm:"primaryKey;autoIncrement:false"`
+	LangID     string
+	PackID     string
+	Locale     string
+	Difficulty string
+	Skill      float64
+	Taboo      bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt
+}
+
+type ChatPack struct {
+	ID        uint  `gorm:"primaryKey"`
+	ChatID    int64 `gorm:"index"`
 	LangID    string
-	PackID    string
-	Locale    string
+	Name      string
+	Words     string
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt
 }
 
+type Report struct {
+	ID        uint `gorm:"primaryKey"`
+	ChatID    int64
+	UserID    int64
+	LangID    string `gorm:"index:idx_report_word"`
+	PackID    string `gorm:"index:idx_report_word"`
+	Part      string
+	Word      string `gorm:"index:idx_report_word"`
+	Reason    string
+	Status    string `gorm:"index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// WordStat accumulates outcomes of rounds with the word.
+type WordStat struct {
+	LangID    string `gorm:"primaryKey"`
+	PackID    string `gorm:"primaryKey"`
+	Word      string `gorm:"primaryKey"`
+	Guessed   int
+	GuessTime float64
+	AiGuessed int
+	AiTurns   int
+	Skipped   int
+	TimedOut  int
+	UpdatedAt time.Time
+}
+
+// DailyResult is the result of the daily challenge played by the user. The result is created
+// when the challenge starts, so it can be played once.
+type DailyResult struct {
+	ID        uint   `gorm:"primaryKey"`
+	Day       int    `gorm:"uniqueIndex:idx_daily_user;index:idx_daily_day"`
+	LangID    string `gorm:"uniqueIndex:idx_daily_user;index:idx_daily_day"`
+	UserID    int64  `gorm:"uniqueIndex:idx_daily_user"`
+	Name      string
+	Turns     int
+	Solved    bool
+	Finished  bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// DailyStreak counts consecutive days the user solved daily challenges in the language.
+type DailyStreak struct {
+	UserID    int64  `gorm:"primaryKey;autoIncrement:false"`
+	LangID    string `gorm:"primaryKey"`
+	Current   int
+	Best      int
+	LastDay   int
+	UpdatedAt time.Time
+}
+
+// GetCurrent returns the current streak, which is broken if the challenge of the previous day wasn't solved.
+func (st *DailyStreak) GetCurrent(day int) int {
+	if st.LastDay < day-1 {
+		return 0
+	}
+
+	return st.Current
+}
+
+// PlayerStat accumulates rounds of the player in all chats.
+type PlayerStat struct {
+	UserID  int64 `gorm:"primaryKey;autoIncrement:false"`
+	Hosted  int
+	Guessed int
+	// BestGuessTime is the fastest guess in seconds, zero if the player has not guessed yet.
+	BestGuessTime float64
+	// AiBestTurns is the fewest AI turns in single player mode, zero if the AI has not guessed yet.
+	AiBestTurns int
+	UpdatedAt   time.Time
+}
+
+// PlayerPackStat counts rounds of the player with the word pack.
+type PlayerPackStat struct {
+	UserID int64  `gorm:"primaryKey;autoIncrement:false"`
+	LangID string `gorm:"primaryKey"`
+	PackID string `gorm:"primaryKey"`
+	Rounds int
+}
+
+// PlayerAchievement is the achievement unlocked by the player.
+type PlayerAchievement struct {
+	UserID        int64  `gorm:"primaryKey;autoIncrement:false"`
+	AchievementID string `gorm:"primaryKey"`
+	CreatedAt     time.Time
+}
+
+// PlayerRating is the rating of the player in the season, in the chat or in all chats if the chat ID is zero.
+type PlayerRating struct {
+	Season    int   `gorm:"primaryKey;autoIncrement:false"`
+	ChatID    int64 `gorm:"primaryKey;autoIncrement:false"`
+	UserID    int64 `gorm:"primaryKey;autoIncrement:false"`
+	Guesser   float64
+	Host      float64
+	Guessed   int
+	Hosted    int
+	UpdatedAt time.Time
+}
+
+// PlayerName is the last known name of the player, ratings of all chats show it.
+type PlayerName struct {
+	UserID    int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	UpdatedAt time.Time
+}
+
 func LoadDatabase(path string, defaultCfg ChatConfig) (*DB, bool) {
 	log := zap.L().Named("db").Sugar()
 	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
@@ -30,7 +169,14 @@ func LoadDatabase(path string, defaultCfg ChatConfig) (*DB, bool) {
 		return nil, false
 	}
 
-	err = db.AutoMigrate(&ChatConfig{})
+	err = migrateChatThreads(db)
+	if err != nil {
+		log.Error(err)
+		return nil, false
+	}
+
+	err = db.AutoMigrate(&ChatConfig{}, &ChatPack{}, &Report{}, &WordStat{}, &DailyResult{}, &DailyStreak{},
+		&PlayerStat{}, &PlayerPackStat{}, &PlayerAchievement{}, &PlayerRating{}, &PlayerName{})
 	if err != nil {
 		log.Error(err)
 		return nil, false
@@ -42,46 +188,386 @@ func LoadDatabase(path string, defaultCfg ChatConfig) (*DB, bool) {
 	}, true
 }
 
-func (db *DB) LoadChatConfig(chatID int64) *ChatConfig {
+// migrateChatThreads adds the thread ID to the primary key of chat configs created before forum topics
+// were supported. SQLite can't change the primary key of the table, so the table is recreated.
+func migrateChatThreads(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&ChatConfig{}) || m.HasColumn(&ChatConfig{}, "ThreadID") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// old databases may lack columns added later, they get default values
+		oldColumns, err := tx.Migrator().ColumnTypes(&ChatConfig{})
+		if err != nil {
+			return err
+		}
+		columns := make([]string, 0, len(oldColumns))
+		for _, col := range oldColumns {
+			columns = append(columns, col.Name())
+		}
+
+		const oldTable = "chat_configs_old"
+		err = tx.Migrator().RenameTable(&ChatConfig{}, oldTable)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Migrator().CreateTable(&ChatConfig{})
+		if err != nil {
+			return err
+		}
+
+		list := strings.Join(columns, ", ")
+		err = tx.Exec(fmt.Sprintf("INSERT INTO chat_configs (%s, thread_id) SELECT %s, 0 FROM %s",
+			list, list, oldTable)).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Migrator().DropTable(oldTable)
+	})
+}
+
+func (db *DB) LoadChatConfig(key ChatKey) *ChatConfig {
 	var cfg ChatConfig
-	db.db.Limit(1).Find(&cfg, chatID)
+	db.whereChat(key).Limit(1).Find(&cfg)
 
-	if cfg.ChatID != chatID {
-		cfg = db.cfg
-		cfg.ChatID = chatID
+	if cfg.ChatID != key.ChatID || cfg.ThreadID != key.ThreadID {
+		cfg = db.newChatConfig(key)
 		db.db.Create(&cfg)
 	}
 
 	return &cfg
 }
 
-func (db *DB) SetWordPack(chatID int64, langID, packID string) {
-	tx := db.db.Model(&ChatConfig{}).Where(chatID).
+func (db *DB) newChatConfig(key ChatKey) ChatConfig {
+	cfg := db.cfg
+	cfg.ChatID = key.ChatID
+	cfg.ThreadID = key.ThreadID
+	return cfg
+}
+
+// whereChat selects the config of the chat or topic. Struct conditions are not used,
+// as gorm skips the zero thread ID in them.
+func (db *DB) whereChat(key ChatKey) *gorm.DB {
+	return db.db.Model(&ChatConfig{}).Where("chat_id = ? AND thread_id = ?", key.ChatID, key.ThreadID)
+}
+
+func (db *DB) SetWordPack(key ChatKey, langID, packID string) {
+	tx := db.whereChat(key).
 		Updates(&ChatConfig{LangID: langID, PackID: packID})
 
 	if tx.RowsAffected < 1 {
-		cfg := db.cfg
-		cfg.ChatID = chatID
+		cfg := db.newChatConfig(key)
 		cfg.LangID = langID
 		cfg.PackID = packID
 		db.db.Create(&cfg)
 	}
 }
 
-func (db *DB) SetLocale(chatID int64, locale string) {
-	tx := db.db.Model(&ChatConfig{}).Where(chatID).
+// ResetWordPack sets the default language and word pack for the chat.
+func (db *DB) ResetWordPack(key ChatKey) {
+	db.SetWordPack(key, db.cfg.LangID, db.cfg.PackID)
+}
+
+// ResetChatPack sets the default language and word pack for all topics of the chat using the chat word pack.
+func (db *DB) ResetChatPack(chatID int64, packID string) int64 {
+	tx := db.db.Model(&ChatConfig{}).Where("chat_id = ? AND pack_id = ?", chatID, packID).
+		Updates(&ChatConfig{LangID: db.cfg.LangID, PackID: db.cfg.PackID})
+	return tx.RowsAffected
+}
+
+func (db *DB) SetLocale(key ChatKey, locale string) {
+	tx := db.whereChat(key).
 		Update("locale", locale)
 
 	if tx.RowsAffected < 1 {
-		cfg := db.cfg
-		cfg.ChatID = chatID
+		cfg := db.newChatConfig(key)
 		cfg.Locale = locale
 		db.db.Create(&cfg)
 	}
 }
 
+func (db *DB) SetDifficulty(key ChatKey, difficulty string) {
+	tx := db.whereChat(key).
+		Updates(map[string]any{"difficulty": difficulty, "skill": 0})
+
+	if tx.RowsAffected < 1 {
+		cfg := db.newChatConfig(key)
+		cfg.Difficulty = difficulty
+		db.db.Create(&cfg)
+	}
+}
+
+func (db *DB) SetSkill(key ChatKey, skill float64) {
+	db.whereChat(key).
+		Update("skill", skill)
+}
+
+func (db *DB) SetTaboo(key ChatKey, taboo bool) {
+	tx := db.whereChat(key).
+		Update("taboo", taboo)
+
+	if tx.RowsAffected < 1 {
+		cfg := db.newChatConfig(key)
+		cfg.Taboo = taboo
+		db.db.Create(&cfg)
+	}
+}
+
 func (db *DB) GetChatCount() int64 {
 	var cnt int64
 	db.db.Model(&ChatConfig{}).Count(&cnt)
 	return cnt
 }
+
+// SaveChatPack creates the chat word pack or replaces words of the pack
+// with the same name and language.
+func (db *DB) SaveChatPack(pack *ChatPack) {
+	var old ChatPack
+	db.db.Where(&ChatPack{ChatID: pack.ChatID, LangID: pack.LangID, Name: pack.Name}).
+		Limit(1).Find(&old)
+
+	if old.ID == 0 {
+		db.db.Create(pack)
+		return
+	}
+
+	pack.ID = old.ID
+	db.db.Model(&old).Update("words", pack.Words)
+}
+
+// LoadChatPacks returns word packs of the chat in the language, or in all languages if langID is empty.
+func (db *DB) LoadChatPacks(chatID int64, langID string) []ChatPack {
+	var packs []ChatPack
+	db.db.Where(&ChatPack{ChatID: chatID, LangID: langID}).Order("id").Find(&packs)
+	return packs
+}
+
+func (db *DB) LoadChatPack(chatID int64, packID uint) (*ChatPack, bool) {
+	var pack ChatPack
+	db.db.Where(&ChatPack{ChatID: chatID}).Limit(1).Find(&pack, packID)
+	return &pack, pack.ID == packID && packID != 0
+}
+
+func (db *DB) CountChatPacks(chatID int64) int64 {
+	var cnt int64
+	db.db.Model(&ChatPack{}).Where(&ChatPack{ChatID: chatID}).Count(&cnt)
+	return cnt
+}
+
+func (db *DB) DeleteChatPack(chatID int64, packID uint) bool {
+	tx := db.db.Where(&ChatPack{ChatID: chatID}).Delete(&ChatPack{}, packID)
+	return tx.RowsAffected > 0
+}
+
+func (db *DB) AddReport(report *Report) {
+	db.db.Create(report)
+}
+
+func (db *DB) LoadReport(id uint) (*Report, bool) {
+	var report Report
+	db.db.Limit(1).Find(&report, id)
+	return &report, report.ID == id && id != 0
+}
+
+// LoadReports returns reports with the status ordered by creation time, or all reports if status is empty.
+func (db *DB) LoadReports(status string) []Report {
+	var reports []Report
+	db.db.Where(&Report{Status: status}).Order("id").Find(&reports)
+	return reports
+}
+
+// SetReportStatus sets the status of all new reports of the word.
+func (db *DB) SetReportStatus(langID, packID, word, status string) int64 {
+	tx := db.db.Model(&Report{}).
+		Where("lang_id = ? AND pack_id = ? AND word = ? AND status = ?", langID, packID, word, reportNew).
+		Update("status", status)
+	return tx.RowsAffected
+}
+
+// AddWordOutcome adds the round outcome to the word statistics and returns the updated statistics.
+func (db *DB) AddWordOutcome(langID, packID, word string, outcome wordOutcome) *WordStat {
+	var st WordStat
+	db.db.Where(&WordStat{LangID: langID, PackID: packID, Word: word}).Limit(1).Find(&st)
+	st.LangID, st.PackID, st.Word = langID, packID, word
+
+	switch {
+	case outcome.result == wordSkipped:
+		st.Skipped++
+	case outcome.result == wordTimedOut:
+		st.TimedOut++
+	case outcome.aiTurns > 0:
+		st.AiGuessed++
+		st.AiTurns += outcome.aiTurns
+	default:
+		st.Guessed++
+		st.GuessTime += outcome.dur.Seconds()
+	}
+
+	db.db.Save(&st)
+
+	return &st
+}
+
+// LoadWordStats returns statistics of the word pack words, or of all words if langID and packID are empty.
+func (db *DB) LoadWordStats(langID, packID string) []WordStat {
+	var stats []WordStat
+	db.db.Where(&WordStat{LangID: langID, PackID: packID}).Find(&stats)
+	return stats
+}
+
+// StartDaily creates the result of the daily challenge. It returns false if the user has played it already.
+func (db *DB) StartDaily(result *DailyResult) bool {
+	tx := db.db.Clauses(clause.OnConflict{DoNothing: true}).Create(result)
+	return tx.Error == nil && tx.RowsAffected > 0
+}
+
+// FinishDaily sets the outcome of the started daily challenge. It returns false if the challenge is finished.
+func (db *DB) FinishDaily(day int, langID string, userID int64, turns int, solved bool) bool {
+	tx := db.db.Model(&DailyResult{}).
+		Where("day = ? AND lang_id = ? AND user_id = ? AND finished = ?", day, langID, userID, false).
+		Updates(map[string]any{"turns": turns, "solved": solved, "finished": true})
+	return tx.RowsAffected > 0
+}
+
+func (db *DB) LoadDailyResult(day int, langID string, userID int64) (*DailyResult, bool) {
+	var result DailyResult
+	db.db.Where("day = ? AND lang_id = ? AND user_id = ?", day, langID, userID).Limit(1).Find(&result)
+	return &result, result.ID != 0
+}
+
+// LoadDailyResults returns solved challenges of the day ordered by turns and solving time.
+func (db *DB) LoadDailyResults(day int, langID string, limit int) []DailyResult {
+	var results []DailyResult
+	db.db.Where("day = ? AND lang_id = ? AND solved = ?", day, langID, true).
+		Order("turns").Order("updated_at").Limit(limit).Find(&results)
+	return results
+}
+
+// CountDailyResults returns the number of players of the daily challenge and the number of solved ones.
+func (db *DB) CountDailyResults(day int, langID string) (int64, int64) {
+	var played, solved int64
+	db.db.Model(&DailyResult{}).Where("day = ? AND lang_id = ?", day, langID).Count(&played)
+	db.db.Model(&DailyResult{}).Where("day = ? AND lang_id = ? AND solved = ?", day, langID, true).Count(&solved)
+	return played, solved
+}
+
+func (db *DB) LoadDailyStreak(userID int64, langID string) *DailyStreak {
+	var st DailyStreak
+	db.db.Where("user_id = ? AND lang_id = ?", userID, langID).Limit(1).Find(&st)
+	st.UserID, st.LangID = userID, langID
+	return &st
+}
+
+// UpdateDailyStreak continues the streak if the challenge is solved on the next day after the last one,
+// and breaks it otherwise.
+func (db *DB) UpdateDailyStreak(userID int64, langID string, day int, solved bool) *DailyStreak {
+	st := db.LoadDailyStreak(userID, langID)
+	switch {
+	case !solved:
+		st.Current = 0
+	case st.LastDay == day-1 && st.Current > 0:
+		st.Current++
+	case st.LastDay != day || st.Current == 0:
+		st.Current = 1
+	}
+	if solved {
+		st.LastDay = day
+		st.Best = max(st.Best, st.Current)
+	}
+
+	db.db.Save(st)
+
+	return st
+}
+
+func (db *DB) LoadPlayerStat(userID int64) *PlayerStat {
+	var st PlayerStat
+	db.db.Limit(1).Find(&st, userID)
+	st.UserID = userID
+	return &st
+}
+
+func (db *DB) SavePlayerStat(st *PlayerStat) {
+	db.db.Save(st)
+}
+
+// AddPlayerPackRound counts the round of the player with the word pack.
+func (db *DB) AddPlayerPackRound(userID int64, langID, packID string) {
+	st := PlayerPackStat{UserID: userID, LangID: langID, PackID: packID, Rounds: 1}
+	db.db.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]any{"rounds": gorm.Expr("rounds + 1")}),
+	}).Create(&st)
+}
+
+// LoadFavoritePack returns the word pack the player has played the most rounds with.
+func (db *DB) LoadFavoritePack(userID int64) (*PlayerPackStat, bool) {
+	var st PlayerPackStat
+	db.db.Where("user_id = ?", userID).Order("rounds DESC").Limit(1).Find(&st)
+	return &st, st.Rounds > 0
+}
+
+// AddAchievement unlocks the achievement. It returns false if the player has unlocked it already.
+func (db *DB) AddAchievement(userID int64, achievementID string) bool {
+	tx := db.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&PlayerAchievement{UserID: userID, AchievementID: achievementID})
+	return tx.Error == nil && tx.RowsAffected > 0
+}
+
+// LoadAchievements returns achievements of the player in the order they were unlocked.
+func (db *DB) LoadAchievements(userID int64) []PlayerAchievement {
+	var achievements []PlayerAchievement
+	db.db.Where("user_id = ?", userID).Order("created_at").Find(&achievements)
+	return achievements
+}
+
+// LoadPlayerRating returns the rating of the player in the season, or false if the player has no rating yet.
+func (db *DB) LoadPlayerRating(season int, chatID, userID int64) (*PlayerRating, bool) {
+	var r PlayerRating
+	tx := db.db.Where("season = ? AND chat_id = ? AND user_id = ?", season, chatID, userID).Limit(1).Find(&r)
+	r.Season, r.ChatID, r.UserID = season, chatID, userID
+	return &r, tx.RowsAffected > 0
+}
+
+func (db *DB) SavePlayerRating(r *PlayerRating) {
+	// Save can't update ratings of all chats, their zero chat ID is taken for a missing primary key
+	db.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(r)
+}
+
+// LoadTopGuessers returns the players with the highest guesser ratings in the season.
+func (db *DB) LoadTopGuessers(season int, chatID int64, limit int) []PlayerRating {
+	var ratings []PlayerRating
+	db.db.Where("season = ? AND chat_id = ? AND guessed > 0", season, chatID).
+		Order("guesser DESC").
+		Limit(limit).
+		Find(&ratings)
+	return ratings
+}
+
+// LoadTopHosts returns the players with the highest host ratings in the season.
+func (db *DB) LoadTopHosts(season int, chatID int64, limit int) []PlayerRating {
+	var ratings []PlayerRating
+	db.db.Where("season = ? AND chat_id = ? AND hosted > 0", season, chatID).
+		Order("host DESC").
+		Limit(limit).
+		Find(&ratings)
+	return ratings
+}
+
+func (db *DB) SetPlayerName(userID int64, name string) {
+	db.db.Save(&PlayerName{UserID: userID, Name: name})
+}
+
+// LoadPlayerNames returns the known names of the players by their IDs.
+func (db *DB) LoadPlayerNames(userIDs []int64) map[int64]string {
+	var names []PlayerName
+	db.db.Where("user_id IN ?", userIDs).Find(&names)
+
+	res := make(map[int64]string, len(names))
+	for _, n := range names {
+		res[n.UserID] = n.Name
+	}
+	return res
+}
@@ -1,8 +1,11 @@
 package croc
 
 import (
+	"github.com/glebarez/sqlite"
 	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -37,17 +40,19 @@ func TestLoadChatConfig(t *testing.T) {
 	db := setupTestDB(t)
 
 	tests := []struct {
-		chatID int64
+		key ChatKey
 	}{
-		{chatID: 2},
-		{chatID: 1},
-		{chatID: 1},
+		{key: ChatKey{ChatID: 2}},
+		{key: ChatKey{ChatID: 1}},
+		{key: ChatKey{ChatID: 1}},
+		{key: ChatKey{ChatID: -1, ThreadID: 5}},
+		{key: ChatKey{ChatID: -1}},
 	}
 
 	for _, tt := range tests {
 		t.Run("LoadChatConfig", func(t *testing.T) {
-			cfg := db.LoadChatConfig(tt.chatID)
-			require.Equal(t, tt.chatID, cfg.ChatID)
+			cfg := db.LoadChatConfig(tt.key)
+			require.Equal(t, tt.key, ChatKey{ChatID: cfg.ChatID, ThreadID: cfg.ThreadID})
 			require.Equal(t, defaultChatCfg.LangID, cfg.LangID)
 			require.Equal(t, defaultChatCfg.PackID, cfg.PackID)
 			require.Equal(t, defaultChatCfg.Locale, cfg.Locale)
@@ -59,19 +64,21 @@ func TestSetWordPack(t *testing.T) {
 	db := setupTestDB(t)
 
 	tests := []struct {
-		chatID int64
+		key    ChatKey
 		langID string
 		packID string
 	}{
-		{chatID: 2, langID: "fr", packID: "pack1"},
-		{chatID: 1, langID: "es", packID: "pack2"},
-		{chatID: 1, langID: "en", packID: "pack3"},
+		{key: ChatKey{ChatID: 2}, langID: "fr", packID: "pack1"},
+		{key: ChatKey{ChatID: 1}, langID: "es", packID: "pack2"},
+		{key: ChatKey{ChatID: 1}, langID: "en", packID: "pack3"},
+		{key: ChatKey{ChatID: -1, ThreadID: 5}, langID: "ru", packID: "pack4"},
+		{key: ChatKey{ChatID: -1, ThreadID: 6}, langID: "en", packID: "pack5"},
 	}
 
 	for _, tt := range tests {
 		t.Run("SetWordPack", func(t *testing.T) {
-			db.SetWordPack(tt.chatID, tt.langID, tt.packID)
-			cfg := db.LoadChatConfig(tt.chatID)
+			db.SetWordPack(tt.key, tt.langID, tt.packID)
+			cfg := db.LoadChatConfig(tt.key)
 			require.Equal(t, tt.langID, cfg.LangID)
 			require.Equal(t, tt.packID, cfg.PackID)
 		})
@@ -82,18 +89,69 @@ func TestSetLocale(t *testing.T) {
 	db := setupTestDB(t)
 
 	tests := []struct {
-		chatID int64
+		key    ChatKey
 		locale string
 	}{
-		{chatID: 2, locale: "fr"},
-		{chatID: 1, locale: "es"},
+		{key: ChatKey{ChatID: 2}, locale: "fr"},
+		{key: ChatKey{ChatID: 1}, locale: "es"},
 	}
 
 	for _, tt := range tests {
 		t.Run("SetLocale", func(t *testing.T) {
-			db.SetLocale(tt.chatID, tt.locale)
-			cfg := db.LoadChatConfig(tt.chatID)
+			db.SetLocale(tt.key, tt.locale)
+			cfg := db.LoadChatConfig(tt.key)
 			require.Equal(t, tt.locale, cfg.Locale)
 		})
 	}
 }
+
+func TestTopicConfigs(t *testing.T) {
+	db := setupTestDB(t)
+
+	db.SetWordPack(ChatKey{ChatID: -1, ThreadID: 5}, "ru", "pack1")
+	db.SetLocale(ChatKey{ChatID: -1, ThreadID: 6}, "ru")
+	db.SetTaboo(ChatKey{ChatID: -1}, true)
+
+	cfg := db.LoadChatConfig(ChatKey{ChatID: -1})
+	require.Equal(t, defaultChatCfg.PackID, cfg.PackID)
+	require.Equal(t, defaultChatCfg.Locale, cfg.Locale)
+	require.True(t, cfg.Taboo)
+
+	cfg = db.LoadChatConfig(ChatKey{ChatID: -1, ThreadID: 5})
+	require.Equal(t, "pack1", cfg.PackID)
+	require.Equal(t, defaultChatCfg.Locale, cfg.Locale)
+	require.False(t, cfg.Taboo)
+
+	cfg = db.LoadChatConfig(ChatKey{ChatID: -1, ThreadID: 6})
+	require.Equal(t, defaultChatCfg.PackID, cfg.PackID)
+	require.Equal(t, "ru", cfg.Locale)
+	require.EqualValues(t, 3, db.GetChatCount())
+}
+
+func TestMigrateChatThreads(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "old.db")
+
+	old, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	require.NoError(t, err)
+	err = old.Exec("CREATE TABLE chat_configs (chat_id integer, lang_id text, pack_id text, locale text, " +
+		"created_at datetime, updated_at datetime, deleted_at datetime, PRIMARY KEY (chat_id))").Error
+	require.NoError(t, err)
+	err = old.Exec("INSERT INTO chat_configs (chat_id, lang_id, pack_id, locale) VALUES (-1, 'ru', 'pack1', 'ru')").Error
+	require.NoError(t, err)
+	sqlDB, err := old.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+
+	db, ok := LoadDatabase(path, defaultChatCfg)
+	require.True(t, ok)
+
+	cfg := db.LoadChatConfig(ChatKey{ChatID: -1})
+	require.Equal(t, "ru", cfg.LangID)
+	require.Equal(t, "pack1", cfg.PackID)
+	require.Equal(t, "ru", cfg.Locale)
+
+	db.SetWordPack(ChatKey{ChatID: -1, ThreadID: 5}, "en", "pack2")
+	require.Equal(t, "pack1", db.LoadChatConfig(ChatKey{ChatID: -1}).PackID)
+	require.Equal(t, "pack2", db.LoadChatConfig(ChatKey{ChatID: -1, ThreadID: 5}).PackID)
+}
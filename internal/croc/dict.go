@@ -1,64 +1,553 @@
 package croc
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	bolt "go.etcd.io/bbolt"
 	"go.uber.org/zap"
+	"os"
+	"strings"
+	"sync"
+	"time"
 )
 
+// TranslationsBucket holds translations of words as <lang>/<part>/<target lang>/<word>.
+const TranslationsBucket = "translations"
+
+// IndexBucket holds lookup indexes of words as <lang>/<index>/<lowercase word>.
+const IndexBucket = "index"
+
+const (
+	// LowercaseIndex maps the lowercase word to its entries as "<part>\t<word>" lines,
+	// the part is empty if the dictionary has no parts of speech.
+	LowercaseIndex = "low"
+	// RedirectIndex maps the lowercase word to the word it redirects to.
+	RedirectIndex = "redirect"
+)
+
+// Fallbacks of the dictionary lookup if the word is not found as is.
+const (
+	fallbackLowercase = "lowercase"
+	fallbackPart      = "part"
+	fallbackRedirect  = "redirect"
+)
+
+// DictEntryVersion is the version of the structured dictionary value.
+// Values without the version are plain text definitions.
+const DictEntryVersion = 1
+
+// DictEntry is the dictionary value of the word.
+type DictEntry struct {
+	Version   int         `json:"v"`
+	Senses    []DictSense `json:"senses"`
+	Synonyms  []string    `json:"synonyms,omitempty"`
+	Etymology string      `json:"etymology,omitempty"`
+	// Source is the name of the dictionary the entry is found in.
+	Source string `json:"source,omitempty"`
+}
+
+type DictSense struct {
+	Gloss    string   `json:"gloss"`
+	Examples []string `json:"examples,omitempty"`
+	Synonyms []string `json:"synonyms,omitempty"`
+}
+
+// ParseDictEntry reads the structured value, or the plain text definition of older dictionaries.
+func ParseDictEntry(value []byte) *DictEntry {
+	if bytes.HasPrefix(value, []byte("{")) {
+		var entry DictEntry
+		err := json.Unmarshal(value, &entry)
+		if err == nil && entry.Version > 0 {
+			return &entry
+		}
+	}
+
+	return &DictEntry{Senses: []DictSense{{Gloss: string(value)}}}
+}
+
+// Text returns the definition as plain text with numbered senses.
+func (entry *DictEntry) Text() string {
+	if len(entry.Senses) == 1 {
+		return entry.Senses[0].Gloss
+	}
+
+	var def strings.Builder
+	for i, sense := range entry.Senses {
+		def.WriteString(fmt.Sprintf("%d) %s\n", i+1, sense.Gloss))
+	}
+
+	return def.String()
+}
+
+// DictCheckInterval is how often the dictionary files are checked for replacement.
+const DictCheckInterval = time.Minute
+
+// dictSource is a dictionary file, the name is shown under its definitions.
+type dictSource struct {
+	name string
+	path string
+	db   *bolt.DB
+}
+
+// dictFiles are the opened dictionaries with their sources in the lookup order.
+type dictFiles struct {
+	defaults []*dictSource
+	langs    map[string][]*dictSource
+	opened   map[string]*bolt.DB
+	infos    map[string]os.FileInfo
+}
+
+// Dict looks words up in the dictionaries of their language in order, or in the default dictionary
+// if the language has none. The dictionaries can be replaced while the bot is running.
 type Dict struct {
-	db  *bolt.DB
-	log *zap.SugaredLogger
+	// mu is held for reading during lookups, so the replaced dictionaries are closed once they finish
+	mu    sync.RWMutex
+	files *dictFiles
+	cfg   Config
+	log   *zap.SugaredLogger
 }
 
+// NewDict opens the default dictionary.
 func NewDict(path string) (*Dict, bool) {
-	log := zap.L().Named("dict").Sugar()
-	db, err := bolt.Open(path, 0400, &bolt.Options{ReadOnly: true})
+	return LoadDict(Config{DictPath: path})
+}
+
+// LoadDict opens the default dictionary and dictionaries of the languages.
+func LoadDict(cfg Config) (*Dict, bool) {
+	d := &Dict{log: zap.L().Named("dict").Sugar()}
+
+	files, ok := d.openFiles(cfg)
+	if !ok {
+		return nil, false
+	}
+
+	d.files = files
+	d.cfg = cfg
+
+	return d, true
+}
+
+func (d *Dict) openFiles(cfg Config) (*dictFiles, bool) {
+	files := &dictFiles{
+		langs:  make(map[string][]*dictSource),
+		opened: make(map[string]*bolt.DB),
+		infos:  make(map[string]os.FileInfo),
+	}
+
+	if cfg.DictPath != "" {
+		src, ok := d.openSource(files, cfg.DictName, cfg.DictPath)
+		if !ok {
+			d.closeFiles(files)
+			return nil, false
+		}
+		files.defaults = append(files.defaults, src)
+	}
+
+	for _, lang := range cfg.Languages {
+		for _, dictCfg := range lang.Dicts {
+			src, ok := d.openSource(files, dictCfg.Name, dictCfg.Path)
+			if !ok {
+				d.closeFiles(files)
+				return nil, false
+			}
+			files.langs[lang.ID] = append(files.langs[lang.ID], src)
+		}
+	}
+
+	return files, true
+}
+
+// openSource opens the dictionary file, once for all languages using it.
+func (d *Dict) openSource(files *dictFiles, name, path string) (*dictSource, bool) {
+	db, ok := files.opened[path]
+	if !ok {
+		info, err := os.Stat(path)
+		if err == nil {
+			db, err = bolt.Open(path, 0400, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+		}
+		if err != nil {
+			d.log.Errorw("can't open dictionary",
+				"path", path,
+				"error", err)
+			return nil, false
+		}
+		files.opened[path] = db
+		files.infos[path] = info
+	}
+
+	return &dictSource{name: name, path: path, db: db}, true
+}
+
+// validateFiles checks that every dictionary has definitions.
+func validateFiles(files *dictFiles) error {
+	for path, db := range files.opened {
+		hasDefs := false
+		err := db.View(func(tx *bolt.Tx) error {
+			return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+				if string(name) != IndexBucket && string(name) != TranslationsBucket {
+					hasDefs = true
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		if !hasDefs {
+			return fmt.Errorf("%s has no definitions", path)
+		}
+	}
+
+	return nil
+}
+
+func (d *Dict) closeFiles(files *dictFiles) {
+	for _, db := range files.opened {
+		err := db.Close()
+		if err != nil {
+			d.log.Warn(err)
+		}
+	}
+}
+
+// Reload opens and validates the dictionaries of the config and replaces the current ones with them.
+// Lookups in progress finish with the replaced dictionaries before they are closed.
+func (d *Dict) Reload(cfg Config) bool {
+	files, ok := d.load(cfg)
+	if !ok {
+		return false
+	}
+
+	d.replace(cfg, files)
+
+	return true
+}
+
+// config returns the config the current dictionaries are loaded with.
+func (d *Dict) config() Config {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.cfg
+}
+
+// load opens and validates the dictionaries of the config without using them yet.
+func (d *Dict) load(cfg Config) (*dictFiles, bool) {
+	files, ok := d.openFiles(cfg)
+	if !ok {
+		return nil, false
+	}
+
+	err := validateFiles(files)
 	if err != nil {
-		log.Error(err)
+		d.log.Errorw("invalid dictionary", "error", err)
+		d.closeFiles(files)
 		return nil, false
 	}
 
-	return &Dict{
-		db:  db,
-		log: log,
-	}, true
+	return files, true
+}
+
+// replace substitutes the current dictionaries with the loaded ones and closes the current ones.
+func (d *Dict) replace(cfg Config, files *dictFiles) {
+	d.mu.Lock()
+	old := d.files
+	d.files = files
+	d.cfg = cfg
+	d.mu.Unlock()
+
+	d.closeFiles(old)
+
+	d.log.Infow("dictionaries reloaded",
+		"files", len(files.opened))
+}
+
+// Watch calls reload once any of the dictionary files is replaced, until the returned function is called.
+// The reload must use the current config, which can be replaced meanwhile.
+func (d *Dict) Watch(interval time.Duration, reload func() bool) func() {
+	d.mu.RLock()
+	seen := make(map[string]os.FileInfo, len(d.files.infos))
+	for path, info := range d.files.infos {
+		seen[path] = info
+	}
+	d.mu.RUnlock()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			d.mu.RLock()
+			paths := make([]string, 0, len(d.files.opened))
+			for path := range d.files.opened {
+				paths = append(paths, path)
+			}
+			d.mu.RUnlock()
+
+			changed := false
+			for _, path := range paths {
+				info, err := os.Stat(path)
+				if err != nil {
+					// the file may be being replaced right now
+					continue
+				}
+
+				old, ok := seen[path]
+				if !ok || !os.SameFile(old, info) || !old.ModTime().Equal(info.ModTime()) {
+					changed = true
+				}
+			}
+
+			// a file which failed to load, e.g. caught half-copied, is retried on the next tick
+			if changed {
+				d.log.Infow("dictionary file replaced")
+				if reload() {
+					d.mu.RLock()
+					for path, info := range d.files.infos {
+						seen[path] = info
+					}
+					d.mu.RUnlock()
+				}
+			}
+		}
+	}()
+
+	return func() { close(done) }
 }
 
-func (d *Dict) FindDefinition(lang, part, query string) (string, bool) {
-	tx, err := d.db.Begin(false)
+// getSources returns dictionaries of the language in the lookup order. The caller must hold the read lock.
+func (d *Dict) getSources(lang string) []*dictSource {
+	if sources, ok := d.files.langs[lang]; ok {
+		return sources
+	}
+
+	return d.files.defaults
+}
+
+// FindDefinition returns the definition as plain text and the name of the dictionary it is found in.
+func (d *Dict) FindDefinition(lang, part, query string) (string, string, bool) {
+	entry, ok := d.FindEntry(lang, part, query)
+	if !ok {
+		return "", "", false
+	}
+
+	return entry.Text(), entry.Source, true
+}
+
+// FindEntry returns the entry of the word from the first dictionary of the language having it.
+// If the word is not found as is, its entries in another case, as other parts of speech
+// and of the word it redirects to are looked up.
+func (d *Dict) FindEntry(lang, part, query string) (*DictEntry, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, src := range d.getSources(lang) {
+		entry, ok := d.findSourceEntry(src, lang, part, query)
+		if ok {
+			entry.Source = src.name
+			return entry, true
+		}
+	}
+
+	d.log.Warnw("definition of the word not found",
+		"query", query,
+		"lang", lang,
+		"part", part)
+	return nil, false
+}
+
+func (d *Dict) findSourceEntry(src *dictSource, lang, part, query string) (*DictEntry, bool) {
+	tx, err := src.db.Begin(false)
+	if err != nil {
+		d.log.Error(err)
+		return nil, false
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	langBkt := tx.Bucket([]byte(lang))
+	if langBkt == nil {
+		d.log.Debugw("bucket does not exist",
+			"path", src.path,
+			"lang", lang)
+		return nil, false
+	}
+
+	index := tx.Bucket([]byte(IndexBucket))
+	if index != nil {
+		index = index.Bucket([]byte(lang))
+	}
+
+	def, fallback := lookupWord(langBkt, index, part, query)
+	if def == nil && index != nil {
+		if target := getIndexValue(index, RedirectIndex, query); target != nil {
+			def, _ = lookupWord(langBkt, index, part, string(target))
+			fallback = fallbackRedirect
+		}
+	}
+
+	if def == nil {
+		return nil, false
+	}
+
+	if fallback != "" {
+		d.log.Infow("definition found by fallback",
+			"query", query,
+			"part", part,
+			"path", src.path,
+			"fallback", fallback)
+	}
+
+	return ParseDictEntry(def), true
+}
+
+// lookupWord returns the definition of the word as is, then of the same part of speech in another case,
+// then of another part of speech. The fallback is empty if the word is found as is.
+func lookupWord(langBkt, index *bolt.Bucket, part, word string) ([]byte, string) {
+	if def := getDefinition(langBkt, part, word); def != nil {
+		return def, ""
+	}
+
+	keys := getIndexValue(index, LowercaseIndex, word)
+	if keys == nil {
+		return nil, ""
+	}
+
+	var other []byte
+	for _, key := range strings.Split(string(keys), "\n") {
+		keyPart, keyWord, _ := strings.Cut(key, "\t")
+		def := getDefinition(langBkt, keyPart, keyWord)
+		if def == nil {
+			continue
+		}
+
+		if keyPart == part {
+			return def, fallbackLowercase
+		}
+
+		if other == nil {
+			other = def
+		}
+	}
+
+	if other != nil {
+		return other, fallbackPart
+	}
+
+	return nil, ""
+}
+
+func getDefinition(langBkt *bolt.Bucket, part, word string) []byte {
+	bkt := langBkt
+	if part != "" {
+		bkt = langBkt.Bucket([]byte(part))
+	}
+	if bkt == nil {
+		return nil
+	}
+
+	return bkt.Get([]byte(word))
+}
+
+func getIndexValue(index *bolt.Bucket, name, word string) []byte {
+	if index == nil {
+		return nil
+	}
+
+	bkt := index.Bucket([]byte(name))
+	if bkt == nil {
+		return nil
+	}
+
+	return bkt.Get([]byte(strings.ToLower(word)))
+}
+
+// FindTranslation returns translations of the word into the target language from the first dictionary having them.
+func (d *Dict) FindTranslation(lang, part, query, targetLang string) (string, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, src := range d.getSources(lang) {
+		tr, ok := d.findSourceTranslation(src, lang, part, query, targetLang)
+		if ok {
+			return tr, true
+		}
+	}
+
+	return "", false
+}
+
+func (d *Dict) findSourceTranslation(src *dictSource, lang, part, query, targetLang string) (string, bool) {
+	tx, err := src.db.Begin(false)
 	if err != nil {
 		d.log.Error(err)
 		return "", false
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	bkt := tx.Bucket([]byte(lang))
+	bkt := tx.Bucket([]byte(TranslationsBucket))
+	if bkt != nil {
+		bkt = bkt.Bucket([]byte(lang))
+	}
 	if bkt != nil && part != "" {
 		bkt = bkt.Bucket([]byte(part))
 	}
+	if bkt != nil {
+		bkt = bkt.Bucket([]byte(targetLang))
+	}
 	if bkt == nil {
-		d.log.Errorw("bucket does not exist",
-			"lang", lang,
-			"part", part)
 		return "", false
 	}
 
-	def := bkt.Get([]byte(query))
-	if def == nil {
-		d.log.Warnw("definition of the word not found",
-			"query", query)
+	tr := bkt.Get([]byte(query))
+	if tr == nil {
 		return "", false
 	}
 
-	res := make([]byte, len(def))
-	copy(res, def)
+	return string(tr), true
+}
+
+// HasBucket reports whether any dictionary of the language has definitions of the part of speech.
+func (d *Dict) HasBucket(lang, part string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 
-	return string(res), true
+	for _, src := range d.getSources(lang) {
+		if d.hasSourceBucket(src, lang, part) {
+			return true
+		}
+	}
+
+	return false
 }
 
-func (d *Dict) Close() {
-	err := d.db.Close()
+func (d *Dict) hasSourceBucket(src *dictSource, lang, part string) bool {
+	tx, err := src.db.Begin(false)
 	if err != nil {
-		d.log.Warn(err)
+		d.log.Error(err)
+		return false
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	bkt := tx.Bucket([]byte(lang))
+	if bkt != nil && part != "" {
+		bkt = bkt.Bucket([]byte(part))
 	}
+
+	return bkt != nil
+}
+
+func (d *Dict) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closeFiles(d.files)
 }
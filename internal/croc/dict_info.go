@@ -0,0 +1,220 @@
+package croc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// DictBucket describes the bucket of definitions, or of translations into the target language.
+type DictBucket struct {
+	Dict   string `json:"dict"`
+	Lang   string `json:"lang"`
+	Part   string `json:"part,omitempty"`
+	Target string `json:"target,omitempty"`
+	Words  int    `json:"words"`
+}
+
+// MissingWords lists words of the word pack without definitions.
+type MissingWords struct {
+	Lang  string   `json:"lang"`
+	Pack  string   `json:"pack"`
+	Part  string   `json:"part,omitempty"`
+	Total int      `json:"total"`
+	Words []string `json:"missing"`
+}
+
+// countWords adds buckets with words found in bkt and its nested buckets.
+func countWords(bkt *bolt.Bucket, base DictBucket, nested func(*DictBucket, string), buckets []DictBucket) []DictBucket {
+	info := base
+	var children []string
+	_ = bkt.ForEach(func(k, v []byte) error {
+		if v == nil {
+			children = append(children, string(k))
+		} else {
+			info.Words++
+		}
+		return nil
+	})
+
+	if info.Words > 0 || len(children) == 0 {
+		buckets = append(buckets, info)
+	}
+
+	for _, name := range children {
+		child := base
+		nested(&child, name)
+		buckets = countWords(bkt.Bucket([]byte(name)), child, nested, buckets)
+	}
+
+	return buckets
+}
+
+// Buckets returns all buckets of the dictionaries with word counts.
+func (d *Dict) Buckets() ([]DictBucket, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	paths := make([]string, 0, len(d.files.opened))
+	for path := range d.files.opened {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var buckets []DictBucket
+	for _, path := range paths {
+		err := d.files.opened[path].View(func(tx *bolt.Tx) error {
+			return tx.ForEach(func(name []byte, bkt *bolt.Bucket) error {
+				if string(name) == IndexBucket {
+					return nil
+				}
+
+				if string(name) == TranslationsBucket {
+					return bkt.ForEach(func(lang, _ []byte) error {
+						// translations are stored as <lang>/<part>/<target>/<word> or <lang>/<target>/<word>
+						buckets = countWords(bkt.Bucket(lang), DictBucket{Dict: path, Lang: string(lang)},
+							func(b *DictBucket, child string) {
+								b.Part, b.Target = b.Target, child
+							}, buckets)
+						return nil
+					})
+				}
+
+				buckets = countWords(bkt, DictBucket{Dict: path, Lang: string(name)}, func(b *DictBucket, child string) {
+					b.Part = child
+				}, buckets)
+				return nil
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return buckets, nil
+}
+
+// InspectDict runs the dictionary inspection command and writes the result to out as text or JSON.
+// Commands are "buckets", "lookup <lang> <part> <word>" and "missing".
+func InspectDict(cfgPath string, args []string, asJSON bool, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("command is required: buckets, lookup or missing")
+	}
+
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		return err
+	}
+
+	dict, ok := LoadDict(cfg)
+	if !ok {
+		return errors.New("can't open dictionaries")
+	}
+	defer dict.Close()
+
+	var result any
+	switch args[0] {
+	case "buckets":
+		buckets, err := dict.Buckets()
+		if err != nil {
+			return err
+		}
+		if !asJSON {
+			return printBuckets(out, buckets)
+		}
+		result = buckets
+	case "lookup":
+		if len(args) != 4 {
+			return errors.New("usage: lookup <lang> <part> <word>, part may be empty")
+		}
+		entry, ok := dict.FindEntry(args[1], args[2], args[3])
+		if !ok {
+			return fmt.Errorf("definition of %q not found", args[3])
+		}
+		if !asJSON {
+			_, err = fmt.Fprintln(out, entry.Text())
+			return err
+		}
+		result = entry
+	case "missing":
+		missing, err := findMissingWords(cfg, dict)
+		if err != nil {
+			return err
+		}
+		if !asJSON {
+			return printMissingWords(out, missing)
+		}
+		result = missing
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+func findMissingWords(cfg Config, dict *Dict) ([]MissingWords, error) {
+	wdb, ok := LoadWordDB(cfg.Languages)
+	if !ok {
+		return nil, errors.New("no word packs loaded")
+	}
+
+	missing := make([]MissingWords, 0)
+	for _, langID := range wdb.GetLanguageIDs() {
+		packIDs, _ := wdb.GetWordPackIDs(langID)
+		for _, packID := range packIDs {
+			pack, _ := wdb.GetWordPack(langID, packID)
+			mw := MissingWords{
+				Lang:  langID,
+				Pack:  packID,
+				Part:  pack.GetPart(),
+				Total: len(pack.words),
+				Words: make([]string, 0),
+			}
+
+			for _, word := range pack.words {
+				if word.Definition != "" {
+					continue
+				}
+				if _, ok := dict.FindEntry(langID, pack.GetPart(), word.Text); !ok {
+					mw.Words = append(mw.Words, word.Text)
+				}
+			}
+			sort.Strings(mw.Words)
+
+			missing = append(missing, mw)
+		}
+	}
+
+	return missing, nil
+}
+
+func printBuckets(out io.Writer, buckets []DictBucket) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "DICT\tLANG\tPART\tTRANSLATIONS\tWORDS")
+	for _, b := range buckets {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", b.Dict, b.Lang, b.Part, b.Target, b.Words)
+	}
+	return w.Flush()
+}
+
+func printMissingWords(out io.Writer, missing []MissingWords) error {
+	for _, mw := range missing {
+		_, err := fmt.Fprintf(out, "%s/%s: %d of %d words without definitions\n",
+			mw.Lang, mw.Pack, len(mw.Words), mw.Total)
+		if err != nil {
+			return err
+		}
+
+		for _, word := range mw.Words {
+			_, _ = fmt.Fprintf(out, "\t%s\n", word)
+		}
+	}
+
+	return nil
+}
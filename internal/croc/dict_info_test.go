@@ -0,0 +1,53 @@
+package croc
+
+import (
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	"testing"
+)
+
+func TestDictBuckets(t *testing.T) {
+	db := setupTestDictDB(t)
+	err := db.Update(func(tx *bolt.Tx) error {
+		put := func(path []string, words ...string) error {
+			bkt, err := tx.CreateBucketIfNotExists([]byte(path[0]))
+			if err != nil {
+				return err
+			}
+			for _, name := range path[1:] {
+				bkt, err = bkt.CreateBucketIfNotExists([]byte(name))
+				if err != nil {
+					return err
+				}
+			}
+			for _, word := range words {
+				err = bkt.Put([]byte(word), []byte("def"))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		if err := put([]string{"en", "noun"}, "apple", "pear"); err != nil {
+			return err
+		}
+		if err := put([]string{"ru"}, "яблоко"); err != nil {
+			return err
+		}
+		return put([]string{TranslationsBucket, "en", "noun", "ru"}, "apple")
+	})
+	require.NoError(t, err)
+
+	path := db.Path()
+	require.NoError(t, db.Close())
+
+	dict := setupTestDict(t, path)
+	buckets, err := dict.Buckets()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []DictBucket{
+		{Dict: path, Lang: "en", Part: "noun", Words: 2},
+		{Dict: path, Lang: "ru", Words: 1},
+		{Dict: path, Lang: "en", Part: "noun", Target: "ru", Words: 1},
+	}, buckets)
+}
@@ -6,7 +6,9 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func setupTestDictDB(t *testing.T) *bolt.DB {
@@ -68,19 +70,254 @@ func TestFindDefinition(t *testing.T) {
 
 	dict := setupTestDict(t, path)
 
-	def, ok := dict.FindDefinition("en", "noun", "apple")
+	def, _, ok := dict.FindDefinition("en", "noun", "apple")
 	require.True(t, ok)
 	require.Equal(t, "a fruit", def)
 
-	def, ok = dict.FindDefinition("fr", "noun", "apple")
+	def, _, ok = dict.FindDefinition("fr", "noun", "apple")
 	require.False(t, ok)
 	require.Empty(t, def)
 
-	def, ok = dict.FindDefinition("en", "noun", "banana")
+	def, _, ok = dict.FindDefinition("en", "noun", "banana")
 	require.False(t, ok)
 	require.Empty(t, def)
 }
 
+func TestFindEntryFallback(t *testing.T) {
+	db := setupTestDictDB(t)
+	err := db.Update(func(tx *bolt.Tx) error {
+		put := func(path []string, key, value string) error {
+			bkt, err := tx.CreateBucketIfNotExists([]byte(path[0]))
+			if err != nil {
+				return err
+			}
+			for _, name := range path[1:] {
+				bkt, err = bkt.CreateBucketIfNotExists([]byte(name))
+				if err != nil {
+					return err
+				}
+			}
+			return bkt.Put([]byte(key), []byte(value))
+		}
+
+		for _, args := range [][]string{
+			{"en", "noun", "Apple", "a company"},
+			{"en", "noun", "apple", "a fruit"},
+			{"en", "verb", "run", "to move fast"},
+			{"en", "noun", "color", "a hue"},
+			{IndexBucket, "en", LowercaseIndex, "apple", "noun\tApple\nnoun\tapple"},
+			{IndexBucket, "en", LowercaseIndex, "run", "verb\trun"},
+			{IndexBucket, "en", LowercaseIndex, "color", "noun\tcolor"},
+			{IndexBucket, "en", RedirectIndex, "colour", "color"},
+		} {
+			err := put(args[:len(args)-2], args[len(args)-2], args[len(args)-1])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	path := db.Path()
+	require.NoError(t, db.Close())
+
+	dict := setupTestDict(t, path)
+
+	def, _, ok := dict.FindDefinition("en", "noun", "Apple")
+	require.True(t, ok)
+	require.Equal(t, "a company", def)
+
+	def, _, ok = dict.FindDefinition("en", "noun", "APPLE")
+	require.True(t, ok)
+	require.Equal(t, "a company", def)
+
+	def, _, ok = dict.FindDefinition("en", "noun", "Run")
+	require.True(t, ok)
+	require.Equal(t, "to move fast", def)
+
+	def, _, ok = dict.FindDefinition("en", "adj", "Colour")
+	require.True(t, ok)
+	require.Equal(t, "a hue", def)
+
+	_, _, ok = dict.FindDefinition("en", "noun", "banana")
+	require.False(t, ok)
+}
+
+func TestLoadDict(t *testing.T) {
+	var paths []string
+	for _, def := range []string{"a learner definition", "a wiki definition"} {
+		db := setupTestDictDB(t)
+		err := db.Update(func(tx *bolt.Tx) error {
+			bkt, err := tx.CreateBucket([]byte("ru"))
+			if err != nil {
+				return err
+			}
+			err = bkt.Put([]byte("кошка"), []byte(def))
+			if err != nil {
+				return err
+			}
+			return bkt.Put([]byte(def), []byte(def))
+		})
+		require.NoError(t, err)
+		paths = append(paths, db.Path())
+		require.NoError(t, db.Close())
+	}
+
+	logger := zaptest.NewLogger(t)
+	zap.ReplaceGlobals(logger)
+
+	dict, ok := LoadDict(Config{
+		DictPath: paths[1],
+		Languages: []LanguageConfig{{
+			ID: "ru",
+			Dicts: []DictConfig{
+				{Name: "Learner", Path: paths[0]},
+				{Name: "Wiktionary", Path: paths[1]},
+			},
+		}},
+	})
+	require.True(t, ok)
+	defer dict.Close()
+
+	def, source, ok := dict.FindDefinition("ru", "", "кошка")
+	require.True(t, ok)
+	require.Equal(t, "a learner definition", def)
+	require.Equal(t, "Learner", source)
+
+	def, source, ok = dict.FindDefinition("ru", "", "a wiki definition")
+	require.True(t, ok)
+	require.Equal(t, "a wiki definition", def)
+	require.Equal(t, "Wiktionary", source)
+
+	_, ok = LoadDict(Config{DictPath: "/invalid/path"})
+	require.False(t, ok)
+}
+
+func writeTestDict(t *testing.T, path, def string) {
+	db, err := bolt.Open(path, 0600, nil)
+	require.NoError(t, err)
+	err = db.Update(func(tx *bolt.Tx) error {
+		if def == "" {
+			return nil
+		}
+		bkt, err := tx.CreateBucket([]byte("en"))
+		if err != nil {
+			return err
+		}
+		return bkt.Put([]byte("apple"), []byte(def))
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+}
+
+func TestDictReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dict.db")
+	writeTestDict(t, path, "a fruit")
+
+	dict := setupTestDict(t, path)
+	defer dict.Close()
+	stop := dict.Watch(10*time.Millisecond, func() bool {
+		return dict.Reload(dict.config())
+	})
+	defer stop()
+
+	def, _, ok := dict.FindDefinition("en", "", "apple")
+	require.True(t, ok)
+	require.Equal(t, "a fruit", def)
+
+	// the dictionary is replaced while it is open
+	newPath := filepath.Join(dir, "dict.db.tmp")
+	writeTestDict(t, newPath, "a tree")
+	require.NoError(t, os.Rename(newPath, path))
+	require.Eventually(t, func() bool {
+		def, _, _ = dict.FindDefinition("en", "", "apple")
+		return def == "a tree"
+	}, time.Second, 10*time.Millisecond)
+
+	// a file which failed to load is retried even if it doesn't change again
+	brokenPath := filepath.Join(dir, "broken.db")
+	writeTestDict(t, brokenPath, "")
+	require.NoError(t, os.Rename(brokenPath, path))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	fixedPath := filepath.Join(dir, "fixed.db")
+	writeTestDict(t, fixedPath, "a red fruit")
+	data, err := os.ReadFile(fixedPath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	require.NoError(t, os.Chtimes(path, info.ModTime(), info.ModTime()))
+	require.Eventually(t, func() bool {
+		def, _, _ = dict.FindDefinition("en", "", "apple")
+		return def == "a red fruit"
+	}, time.Second, 10*time.Millisecond)
+
+	// dictionaries without definitions are not taken
+	emptyPath := filepath.Join(dir, "empty.db")
+	writeTestDict(t, emptyPath, "")
+	require.False(t, dict.Reload(Config{DictPath: emptyPath}))
+	require.False(t, dict.Reload(Config{DictPath: "/invalid/path"}))
+
+	def, _, ok = dict.FindDefinition("en", "", "apple")
+	require.True(t, ok)
+	require.Equal(t, "a red fruit", def)
+}
+
+func TestFindTranslation(t *testing.T) {
+	db := setupTestDictDB(t)
+	err := db.Update(func(tx *bolt.Tx) error {
+		bkt, err := tx.CreateBucket([]byte(TranslationsBucket))
+		if err != nil {
+			return err
+		}
+		for _, name := range []string{"en", "noun", "ru"} {
+			bkt, err = bkt.CreateBucket([]byte(name))
+			if err != nil {
+				return err
+			}
+		}
+		return bkt.Put([]byte("apple"), []byte("яблоко"))
+	})
+	require.NoError(t, err)
+
+	path := db.Path()
+	require.NoError(t, db.Close())
+
+	dict := setupTestDict(t, path)
+
+	tr, ok := dict.FindTranslation("en", "noun", "apple", "ru")
+	require.True(t, ok)
+	require.Equal(t, "яблоко", tr)
+
+	_, ok = dict.FindTranslation("en", "noun", "apple", "de")
+	require.False(t, ok)
+
+	_, ok = dict.FindTranslation("en", "verb", "apple", "ru")
+	require.False(t, ok)
+}
+
+func TestParseDictEntry(t *testing.T) {
+	entry := ParseDictEntry([]byte("a fruit"))
+	require.Equal(t, 0, entry.Version)
+	require.Equal(t, "a fruit", entry.Text())
+
+	entry = ParseDictEntry([]byte("{not json"))
+	require.Equal(t, "{not json", entry.Text())
+
+	value := `{"v":1,"senses":[{"gloss":"a fruit","examples":["an apple a day"]},{"gloss":"a tree"}],` +
+		`"synonyms":["pome"],"etymology":"From Old English."}`
+	entry = ParseDictEntry([]byte(value))
+	require.Equal(t, DictEntryVersion, entry.Version)
+	require.Len(t, entry.Senses, 2)
+	require.Equal(t, []string{"an apple a day"}, entry.Senses[0].Examples)
+	require.Equal(t, []string{"pome"}, entry.Synonyms)
+	require.Equal(t, "From Old English.", entry.Etymology)
+	require.Equal(t, "1) a fruit\n2) a tree\n", entry.Text())
+}
+
 func TestClose(t *testing.T) {
 	db := setupTestDictDB(t)
 	path := db.Path()
@@ -89,7 +326,7 @@ func TestClose(t *testing.T) {
 	dict := setupTestDict(t, path)
 	dict.Close()
 
-	err := dict.db.Update(func(tx *bolt.Tx) error {
+	err := dict.files.defaults[0].db.Update(func(tx *bolt.Tx) error {
 		_, err := tx.CreateBucket([]byte("test"))
 		return err
 	})
@@ -0,0 +1,425 @@
+package croc
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	btnDifficultyAny    = &i18n.Message{ID: "btn_difficulty_any", Other: "Any"}
+	btnDifficultyEasy   = &i18n.Message{ID: "btn_difficulty_easy", Other: "Easy"}
+	btnDifficultyMedium = &i18n.Message{ID: "btn_difficulty_medium", Other: "Medium"}
+	btnDifficultyHard   = &i18n.Message{ID: "btn_difficulty_hard", Other: "Hard"}
+	btnDifficultyAuto   = &i18n.Message{ID: "btn_difficulty_auto", Other: "Adaptive"}
+	msgCurrDifficulty   = &i18n.Message{
+		ID:    "msg_curr_difficulty",
+		Other: "Current word difficulty is <b>{{.difficulty}}</b>.",
+	}
+	msgDifficultyChanged = &i18n.Message{ID: "msg_difficulty_changed", Other: "Difficulty changed."}
+)
+
+const (
+	difficultyAny    = ""
+	difficultyEasy   = "easy"
+	difficultyMedium = "medium"
+	difficultyHard   = "hard"
+	difficultyAuto   = "auto"
+)
+
+var difficultyButtons = []struct {
+	difficulty string
+	msg        *i18n.Message
+}{
+	{difficultyAny, btnDifficultyAny},
+	{difficultyEasy, btnDifficultyEasy},
+	{difficultyMedium, btnDifficultyMedium},
+	{difficultyHard, btnDifficultyHard},
+	{difficultyAuto, btnDifficultyAuto},
+}
+
+var difficultyTargets = map[string]float64{
+	difficultyEasy:   0.25,
+	difficultyMedium: 0.5,
+	difficultyHard:   0.75,
+}
+
+const (
+	// fastGuessTime is the guess time of a word with medium difficulty.
+	fastGuessTime = time.Minute
+	// fastAiTurns is the number of messages to the AI for a word with medium difficulty.
+	fastAiTurns = 3
+	// priorWeight is the number of rounds after which observed outcomes outweigh the prior difficulty.
+	priorWeight = 3
+	// difficultySpread defines how strictly words are picked around the target difficulty.
+	difficultySpread = 0.15
+	// skillRate is how fast the learned chat skill follows the outcomes.
+	skillRate = 0.05
+	// maxWordDifficulty is the hardest difficulty of words in word packs, the easiest is 1.
+	maxWordDifficulty = 5
+)
+
+type wordResult int
+
+const (
+	wordGuessed wordResult = iota
+	wordSkipped
+	wordTimedOut
+)
+
+type wordOutcome struct {
+	result  wordResult
+	dur     time.Duration
+	aiTurns int
+}
+
+// wordPrior returns the difficulty of the word set in the word pack from 0 to 1,
+// or the medium difficulty if it isn't set.
+func wordPrior(word Word) float64 {
+	if word.Difficulty <= 0 {
+		return 0.5
+	}
+
+	return float64(min(word.Difficulty, maxWordDifficulty)-1) / (maxWordDifficulty - 1)
+}
+
+// wordDifficulty estimates the word difficulty from 0 (easy) to 1 (hard).
+// The prior is the difficulty of the word before any rounds.
+func wordDifficulty(st *WordStat, prior float64) float64 {
+	rounds := float64(st.Guessed + st.AiGuessed + st.Skipped + st.TimedOut)
+	if rounds == 0 {
+		return prior
+	}
+
+	fail := float64(st.Skipped+st.TimedOut) / rounds
+
+	var slow float64
+	var slowCnt int
+	if st.Guessed > 0 {
+		avg := st.GuessTime / float64(st.Guessed)
+		slow += avg / (avg + fastGuessTime.Seconds())
+		slowCnt++
+	}
+	if st.AiGuessed > 0 {
+		avg := float64(st.AiTurns) / float64(st.AiGuessed)
+		slow += avg / (avg + fastAiTurns)
+		slowCnt++
+	}
+	if slowCnt > 0 {
+		slow /= float64(slowCnt)
+	} else {
+		slow = 1
+	}
+
+	raw := fail + (1-fail)*slow
+
+	return (rounds*raw + priorWeight*prior) / (rounds + priorWeight)
+}
+
+// outcomePerformance returns how well the word was solved, from 0 (failed) to 1 (instantly).
+func outcomePerformance(outcome wordOutcome) float64 {
+	if outcome.result != wordGuessed {
+		return 0
+	}
+
+	if outcome.aiTurns > 0 {
+		turns := float64(outcome.aiTurns)
+		return 1 - turns/(turns+fastAiTurns)
+	}
+
+	secs := outcome.dur.Seconds()
+	return 1 - secs/(secs+fastGuessTime.Seconds())
+}
+
+func difficultyWeight(difficulty, target float64) float64 {
+	diff := difficulty - target
+	return math.Exp(-diff * diff / (2 * difficultySpread * difficultySpread))
+}
+
+// Difficulty keeps word outcome statistics and picks words for the chat target difficulty.
+type Difficulty struct {
+	db     *DB
+	mu     sync.Mutex
+	scores map[string]map[string]float64
+	log    *zap.SugaredLogger
+}
+
+func NewDifficulty(db *DB) *Difficulty {
+	return &Difficulty{
+		db:     db,
+		scores: make(map[string]map[string]float64),
+		log:    zap.L().Named("difficulty").Sugar(),
+	}
+}
+
+// packPriors returns the difficulties of the pack words set in the word pack.
+func packPriors(pack *WordPack) map[string]float64 {
+	priors := make(map[string]float64)
+	for _, word := range pack.words {
+		if word.Difficulty > 0 {
+			priors[word.Text] = wordPrior(word)
+		}
+	}
+
+	return priors
+}
+
+// getPrior returns the prior difficulty of the word, medium if it isn't set.
+func getPrior(priors map[string]float64, word string) float64 {
+	prior, ok := priors[word]
+	if !ok {
+		return 0.5
+	}
+
+	return prior
+}
+
+// packScores returns the cached difficulties of the pack words with statistics. The caller must hold the lock.
+func (d *Difficulty) packScores(pack *WordPack) map[string]float64 {
+	key := pack.GetLangID() + "/" + pack.GetPackID()
+	scores, ok := d.scores[key]
+	if ok {
+		return scores
+	}
+
+	priors := packPriors(pack)
+	stats := d.db.LoadWordStats(pack.GetLangID(), pack.GetPackID())
+	scores = make(map[string]float64, len(stats))
+	for i := range stats {
+		scores[stats[i].Word] = wordDifficulty(&stats[i], getPrior(priors, stats[i].Word))
+	}
+	d.scores[key] = scores
+
+	return scores
+}
+
+// Reset drops the cached difficulties, so they are computed again with the priors of reloaded word packs.
+func (d *Difficulty) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.scores = make(map[string]map[string]float64)
+}
+
+// GetTarget returns the target difficulty of the chat, or false if words should be picked randomly.
+func (d *Difficulty) GetTarget(key ChatKey) (float64, bool) {
+	cfg := d.db.LoadChatConfig(key)
+	if cfg.Difficulty == difficultyAuto {
+		return 0.5 + cfg.Skill, true
+	}
+
+	target, ok := difficultyTargets[cfg.Difficulty]
+	return target, ok
+}
+
+func (d *Difficulty) GetWord(key ChatKey, pack *WordPack) Word {
+	target, ok := d.GetTarget(key)
+	if !ok {
+		return pack.GetWord()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	scores := d.packScores(pack)
+	return pack.GetWeightedWord(func(word Word) float64 {
+		score, ok := scores[word.Text]
+		if !ok {
+			score = wordPrior(word)
+		}
+
+		return difficultyWeight(score, target)
+	})
+}
+
+// AddOutcome records the outcome of the round and returns the difficulty of the word before the round.
+// Words of chat packs have medium difficulty.
+func (d *Difficulty) AddOutcome(key ChatKey, pack *WordPack, word string, outcome wordOutcome) float64 {
+	if isChatPackID(pack.GetPackID()) {
+		return 0.5
+	}
+
+	st := d.db.AddWordOutcome(pack.GetLangID(), pack.GetPackID(), word, outcome)
+
+	entry, _ := pack.FindWord(word)
+	prior := wordPrior(entry)
+
+	d.mu.Lock()
+	scores := d.packScores(pack)
+	oldScore, ok := scores[word]
+	if !ok {
+		oldScore = prior
+	}
+	scores[word] = wordDifficulty(st, prior)
+	d.mu.Unlock()
+
+	cfg := d.db.LoadChatConfig(key)
+	if cfg.Difficulty == difficultyAuto {
+		skill := cfg.Skill + skillRate*(outcomePerformance(outcome)-(1-oldScore))
+		skill = math.Max(-0.5, math.Min(0.5, skill))
+		d.db.SetSkill(key, skill)
+	}
+
+	d.log.Infow("word outcome",
+		"chat_id", key.ChatID,
+		"thread_id", key.ThreadID,
+		"lang_id", pack.GetLangID(),
+		"pack_id", pack.GetPackID(),
+		"word", word,
+		"result", outcome.result,
+		"difficulty", scores[word])
+
+	return oldScore
+}
+
+func isDifficulty(difficulty string) bool {
+	for _, item := range difficultyButtons {
+		if item.difficulty == difficulty {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newDifficultyMenu(st *botState, locale string) *tele.ReplyMarkup {
+	menu := &tele.ReplyMarkup{}
+	rows := make([]tele.Row, 0, len(difficultyButtons))
+	for _, item := range difficultyButtons {
+		btn := menu.Data(st.tr(item.msg, locale), "difficulty", item.difficulty)
+		rows = append(rows, menu.Row(btn))
+	}
+	menu.Inline(rows...)
+
+	return menu
+}
+
+func (bot *Bot) getDifficultyMessage(difficulty, locale string) string {
+	name := difficulty
+	for _, item := range difficultyButtons {
+		if item.difficulty == difficulty {
+			name = bot.tr(item.msg, locale)
+		}
+	}
+
+	lc := &i18n.LocalizeConfig{
+		DefaultMessage: msgCurrDifficulty,
+		TemplateData: map[string]string{
+			"difficulty": name,
+		},
+	}
+
+	return bot.trCfg(lc, locale)
+}
+
+func (bot *Bot) showDifficultyMenu(c tele.Context) error {
+	cfg := bot.db.LoadChatConfig(getChatKey(c))
+	msg := bot.getDifficultyMessage(cfg.Difficulty, cfg.Locale)
+
+	return c.Send(msg, bot.state.Load().difficultyMenus[cfg.Locale], tele.ModeHTML)
+}
+
+func (bot *Bot) changeDifficulty(c tele.Context) error {
+	difficulty := c.Data()
+	if !isDifficulty(difficulty) {
+		return c.Respond()
+	}
+
+	bot.db.SetDifficulty(getChatKey(c), difficulty)
+
+	bot.log.Infow("difficulty changed",
+		"chat_id", c.Chat().ID,
+		"user_id", c.Sender().ID,
+		"difficulty", difficulty)
+
+	locale := bot.getLocale(c)
+	err := c.Respond(&tele.CallbackResponse{Text: bot.tr(msgDifficultyChanged, locale)})
+	if err != nil {
+		return err
+	}
+
+	return c.Edit(bot.getDifficultyMessage(difficulty, locale), tele.ModeHTML)
+}
+
+// ExportDifficulty writes word statistics and computed difficulties from the bot database as CSV.
+func ExportDifficulty(cfgPath string, out io.Writer) error {
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		return err
+	}
+
+	db, ok := LoadDatabase(cfg.DBPath, ChatConfig{})
+	if !ok {
+		return fmt.Errorf("can't load database %s", cfg.DBPath)
+	}
+
+	// words of packs which can't be loaded have medium prior difficulty
+	priors := make(map[string]map[string]float64)
+	if wdb, ok := LoadWordDB(cfg.Languages); ok {
+		for _, langID := range wdb.GetLanguageIDs() {
+			packIDs, _ := wdb.GetWordPackIDs(langID)
+			for _, packID := range packIDs {
+				pack, _ := wdb.GetWordPack(langID, packID)
+				priors[langID+"/"+packID] = packPriors(pack)
+			}
+		}
+	}
+
+	stats := db.LoadWordStats("", "")
+	scores := make([]float64, len(stats))
+	for i := range stats {
+		st := &stats[i]
+		scores[i] = wordDifficulty(st, getPrior(priors[st.LangID+"/"+st.PackID], st.Word))
+	}
+
+	order := make([]int, len(stats))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := &stats[order[i]], &stats[order[j]]
+		if a.LangID != b.LangID {
+			return a.LangID < b.LangID
+		}
+		if a.PackID != b.PackID {
+			return a.PackID < b.PackID
+		}
+		return scores[order[i]] < scores[order[j]]
+	})
+
+	w := csv.NewWriter(out)
+	_ = w.Write([]string{"lang_id", "pack_id", "word", "guessed", "ai_guessed", "skipped", "timed_out",
+		"avg_guess_time", "avg_ai_turns", "difficulty"})
+	for _, i := range order {
+		st := &stats[i]
+		var avgTime, avgTurns float64
+		if st.Guessed > 0 {
+			avgTime = st.GuessTime / float64(st.Guessed)
+		}
+		if st.AiGuessed > 0 {
+			avgTurns = float64(st.AiTurns) / float64(st.AiGuessed)
+		}
+
+		_ = w.Write([]string{
+			st.LangID, st.PackID, st.Word,
+			strconv.Itoa(st.Guessed),
+			strconv.Itoa(st.AiGuessed),
+			strconv.Itoa(st.Skipped),
+			strconv.Itoa(st.TimedOut),
+			strconv.FormatFloat(avgTime, 'f', 1, 64),
+			strconv.FormatFloat(avgTurns, 'f', 1, 64),
+			strconv.FormatFloat(scores[i], 'f', 3, 64),
+		})
+	}
+	w.Flush()
+
+	return w.Error()
+}
@@ -0,0 +1,121 @@
+package croc
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestWordDifficulty(t *testing.T) {
+	require.Equal(t, 0.5, wordDifficulty(&WordStat{}, 0.5))
+
+	easy := wordDifficulty(&WordStat{Guessed: 10, GuessTime: 100}, 0.5)
+	hard := wordDifficulty(&WordStat{Guessed: 2, GuessTime: 600, Skipped: 5, TimedOut: 3}, 0.5)
+	require.Less(t, easy, 0.5)
+	require.Greater(t, hard, 0.5)
+
+	fewAiTurns := wordDifficulty(&WordStat{AiGuessed: 5, AiTurns: 5}, 0.5)
+	manyAiTurns := wordDifficulty(&WordStat{AiGuessed: 5, AiTurns: 50}, 0.5)
+	require.Less(t, fewAiTurns, manyAiTurns)
+
+	// a single round should not move the word far from the medium difficulty
+	once := wordDifficulty(&WordStat{Skipped: 1}, 0.5)
+	require.Less(t, once, 0.7)
+
+	// the difficulty set in the word pack is the prior of the observed difficulty
+	require.Equal(t, 0.0, wordPrior(Word{Difficulty: 1}))
+	require.Equal(t, 1.0, wordPrior(Word{Difficulty: maxWordDifficulty}))
+	require.Equal(t, 0.5, wordPrior(Word{}))
+	require.Equal(t, 0.0, wordDifficulty(&WordStat{}, 0))
+	require.Less(t, wordDifficulty(&WordStat{Skipped: 1}, 0), once)
+}
+
+func TestGetWeightedWord(t *testing.T) {
+	pack := &WordPack{
+		words: []Word{{Text: "easy"}, {Text: "hard"}},
+	}
+
+	for i := 0; i < 20; i++ {
+		word := pack.GetWeightedWord(func(word Word) float64 {
+			if word.Text == "hard" {
+				return 1
+			}
+			return 0
+		})
+		require.Equal(t, "hard", word.Text)
+	}
+
+	word := pack.GetWeightedWord(func(Word) float64 { return 0 })
+	require.NotEmpty(t, word.Text)
+}
+
+func TestDifficulty(t *testing.T) {
+	db := setupTestDB(t)
+	diff := NewDifficulty(db)
+	pack := &WordPack{
+		langID: "en",
+		packID: "default",
+		words:  []Word{{Text: "easy"}, {Text: "hard"}},
+	}
+
+	_, ok := diff.GetTarget(ChatKey{ChatID: 1})
+	require.False(t, ok)
+
+	for i := 0; i < 10; i++ {
+		diff.AddOutcome(ChatKey{ChatID: -1}, pack, "easy", wordOutcome{result: wordGuessed, dur: 5 * time.Second})
+		diff.AddOutcome(ChatKey{ChatID: -1}, pack, "hard", wordOutcome{result: wordSkipped})
+	}
+	diff.AddOutcome(ChatKey{ChatID: 1}, pack, "easy", wordOutcome{result: wordGuessed, aiTurns: 2})
+
+	stats := db.LoadWordStats("en", "default")
+	require.Len(t, stats, 2)
+	for _, st := range stats {
+		if st.Word == "easy" {
+			require.Equal(t, 10, st.Guessed)
+			require.Equal(t, 1, st.AiGuessed)
+			require.Equal(t, 2, st.AiTurns)
+		} else {
+			require.Equal(t, 10, st.Skipped)
+		}
+	}
+
+	db.SetDifficulty(ChatKey{ChatID: 1}, difficultyHard)
+	target, ok := diff.GetTarget(ChatKey{ChatID: 1})
+	require.True(t, ok)
+	require.Equal(t, 0.75, target)
+	// words far from the target are still picked, though rarely
+	diff.mu.Lock()
+	scores := diff.packScores(pack)
+	diff.mu.Unlock()
+	easyWeight := difficultyWeight(scores["easy"], target)
+	require.Positive(t, easyWeight)
+	require.Greater(t, difficultyWeight(scores["hard"], target), 10*easyWeight)
+
+	db.SetDifficulty(ChatKey{ChatID: 1}, difficultyAuto)
+	diff.AddOutcome(ChatKey{ChatID: 1}, pack, "hard", wordOutcome{result: wordGuessed, aiTurns: 1})
+	target, ok = diff.GetTarget(ChatKey{ChatID: 1})
+	require.True(t, ok)
+	require.Greater(t, target, 0.5)
+}
+
+func TestDifficultyReset(t *testing.T) {
+	db := setupTestDB(t)
+	diff := NewDifficulty(db)
+	pack := &WordPack{langID: "en", packID: "default", words: []Word{{Text: "cat", Difficulty: 1}}}
+	diff.AddOutcome(ChatKey{ChatID: 1}, pack, "cat", wordOutcome{result: wordSkipped})
+
+	diff.mu.Lock()
+	easy := diff.packScores(pack)["cat"]
+	diff.mu.Unlock()
+
+	// the reloaded pack sets another prior, which is used only after the reset
+	pack = &WordPack{langID: "en", packID: "default", words: []Word{{Text: "cat", Difficulty: maxWordDifficulty}}}
+	diff.mu.Lock()
+	require.Equal(t, easy, diff.packScores(pack)["cat"])
+	diff.mu.Unlock()
+
+	diff.Reset()
+	diff.mu.Lock()
+	require.Greater(t, diff.packScores(pack)["cat"], easy)
+	diff.mu.Unlock()
+}
@@ -0,0 +1,156 @@
+package croc
+
+import (
+	"github.com/erni27/imcache"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	tele "gopkg.in/telebot.v3"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	msgGuessThrottled = &i18n.Message{
+		ID:    "msg_guess_throttled",
+		Other: "{{.name}}, you are guessing too fast. Your guesses are ignored for a while.",
+	}
+	msgGuessLines = &i18n.Message{ID: "msg_guess_lines", Other: "{{.name}}, please send one guess per message."}
+)
+
+type floodReason string
+
+const (
+	floodNone  floodReason = ""
+	floodLines floodReason = "lines"
+	floodUser  floodReason = "user"
+	floodChat  floodReason = "chat"
+)
+
+// floodCleanup is the interval of removing counters of past windows.
+const floodCleanup = 5 * time.Minute
+
+type floodCounter struct {
+	count    int
+	notified bool
+}
+
+// FloodGuard limits guesses of users and chats within fixed time windows, and rejects multi-line guesses.
+type FloodGuard struct {
+	mu    sync.Mutex
+	cfg   FloodConfig
+	users *imcache.Cache[int64, *floodCounter]
+	chats *imcache.Cache[ChatKey, *floodCounter]
+}
+
+func NewFloodGuard(cfg FloodConfig) *FloodGuard {
+	fg := &FloodGuard{
+		users: imcache.New[int64, *floodCounter](imcache.WithCleanerOption[int64, *floodCounter](floodCleanup)),
+		chats: imcache.New[ChatKey, *floodCounter](imcache.WithCleanerOption[ChatKey, *floodCounter](floodCleanup)),
+	}
+	fg.SetConfig(cfg)
+
+	return fg
+}
+
+// SetConfig replaces the limits. Counters of the current windows are kept.
+func (fg *FloodGuard) SetConfig(cfg FloodConfig) {
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.UserGuesses <= 0 {
+		cfg.UserGuesses = 20
+	}
+	if cfg.ChatGuesses <= 0 {
+		cfg.ChatGuesses = 120
+	}
+	if cfg.MaxLines <= 0 {
+		cfg.MaxLines = 2
+	}
+
+	fg.mu.Lock()
+	defer fg.mu.Unlock()
+
+	fg.cfg = cfg
+}
+
+// notifyOnce reports whether the user should be told about the rejected guess, once per window.
+func notifyOnce(user *floodCounter) bool {
+	if user.notified {
+		return false
+	}
+
+	user.notified = true
+	return true
+}
+
+// CheckGuess counts the guess and returns the reason to reject it, and whether the user should be notified.
+// Rejected guesses of the user don't count towards the chat limit.
+func (fg *FloodGuard) CheckGuess(key ChatKey, userID int64, text string) (floodReason, bool) {
+	fg.mu.Lock()
+	defer fg.mu.Unlock()
+
+	exp := imcache.WithExpiration(fg.cfg.Window)
+	user, _ := fg.users.GetOrSet(userID, &floodCounter{}, exp)
+
+	if strings.Count(strings.TrimSpace(text), "\n") >= fg.cfg.MaxLines {
+		return floodLines, notifyOnce(user)
+	}
+
+	user.count++
+	if user.count > fg.cfg.UserGuesses {
+		return floodUser, notifyOnce(user)
+	}
+
+	chat, _ := fg.chats.GetOrSet(key, &floodCounter{}, exp)
+	chat.count++
+	if chat.count > fg.cfg.ChatGuesses {
+		return floodChat, notifyOnce(user)
+	}
+
+	return floodNone, false
+}
+
+// isIgnoredGuess reports whether the message comes from a bot or is forwarded, so it can't be a guess.
+// Messages of anonymous admins and channels are sent by bots too.
+func isIgnoredGuess(c tele.Context) bool {
+	msg := c.Message()
+	return c.Sender() == nil || c.Sender().IsBot || msg == nil || msg.Via != nil || msg.IsForwarded()
+}
+
+// allowGuess applies the flood limits to the guess of the player, and notifies the throttled player.
+// Messages of the host explain the word and are not limited.
+func (bot *Bot) allowGuess(c tele.Context) (bool, error) {
+	key := getChatKey(c)
+	if bot.game.IsHost(key, c.Sender().ID) {
+		return true, nil
+	}
+
+	reason, notify := bot.flood.CheckGuess(key, c.Sender().ID, c.Text())
+	if reason == floodNone {
+		return true, nil
+	}
+
+	bot.throttledCount.Add(1)
+	if !notify {
+		return false, nil
+	}
+
+	bot.log.Infow("guesses throttled",
+		"chat_id", key.ChatID,
+		"thread_id", key.ThreadID,
+		"user_id", c.Sender().ID,
+		"reason", reason)
+
+	msg := msgGuessThrottled
+	if reason == floodLines {
+		msg = msgGuessLines
+	}
+	lc := &i18n.LocalizeConfig{
+		DefaultMessage: msg,
+		TemplateData: map[string]string{
+			"name": printUserName(c.Sender()),
+		},
+	}
+
+	return false, c.Reply(bot.trCfg(lc, bot.getLocale(c)), tele.ModeHTML)
+}
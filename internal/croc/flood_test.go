@@ -0,0 +1,36 @@
+package croc
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestFloodGuard(t *testing.T) {
+	fg := NewFloodGuard(FloodConfig{Window: time.Hour, UserGuesses: 2, ChatGuesses: 3, MaxLines: 2})
+	chat := ChatKey{ChatID: -1}
+
+	reason, notify := fg.CheckGuess(chat, 1, "cat\ndog\ncow")
+	require.Equal(t, floodLines, reason)
+	require.True(t, notify)
+
+	for i := 0; i < 2; i++ {
+		reason, _ = fg.CheckGuess(chat, 1, "cat\ndog")
+		require.Equal(t, floodNone, reason)
+	}
+
+	// the user was notified in this window already
+	reason, notify = fg.CheckGuess(chat, 1, "cat")
+	require.Equal(t, floodUser, reason)
+	require.False(t, notify)
+
+	reason, _ = fg.CheckGuess(chat, 2, "cat")
+	require.Equal(t, floodNone, reason)
+	reason, notify = fg.CheckGuess(chat, 2, "cat")
+	require.Equal(t, floodChat, reason)
+	require.True(t, notify)
+
+	// topics of the forum have their own limits
+	reason, _ = fg.CheckGuess(ChatKey{ChatID: -1, ThreadID: 5}, 3, "cat")
+	require.Equal(t, floodNone, reason)
+}
@@ -7,20 +7,37 @@ import (
 	"time"
 )
 
+// dailyRound is the daily challenge played instead of the usual round.
+type dailyRound struct {
+	day int
+	// prevPack is restored when the challenge ends
+	prevPack *WordPack
+}
+
 type gameConfig struct {
-	pack   *WordPack
-	word   string
-	def    string
-	hostID int64
+	pack      *WordPack
+	word      Word
+	def       string
+	hostID    int64
+	startedAt time.Time
+	guesses   int
+	tabooMode bool
+	taboo     []string
+	daily     *dailyRound
 }
 
 func (gc *gameConfig) isActive() bool {
-	return gc.word != ""
+	return gc.word.Text != ""
 }
 
 func (gc *gameConfig) setNotActive() {
-	gc.word = ""
+	gc.word = Word{}
 	gc.def = ""
+	gc.taboo = nil
+	if gc.daily != nil {
+		gc.pack = gc.daily.prevPack
+		gc.daily = nil
+	}
 }
 
 func (gc *gameConfig) hasDefinition() bool {
@@ -36,62 +53,170 @@ func (gc *gameConfig) checkGuess(playerID int64, guess string) bool {
 		return false
 	}
 
-	if len(guess) > len(gc.word)*2 {
-		return false
+	if matchesAnswer(guess, gc.word.Text) {
+		return true
 	}
 
-	guess = strings.Trim(guess, "!?,;:.^&/\\\n\t ")
-	if strings.ToLower(guess) != strings.ToLower(gc.word) {
+	for _, answer := range gc.word.Answers {
+		if matchesAnswer(guess, answer) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesAnswer(guess, answer string) bool {
+	if len(guess) > len(answer)*2 {
 		return false
 	}
 
-	return true
+	guess = strings.Trim(guess, "!?,;:.^&/\\\n\t ")
+	return strings.ToLower(guess) == strings.ToLower(answer)
 }
 
 type Game struct {
-	games imcache.Cache[int64, *gameConfig]
-	db    *DB
-	wdb   *WordDB
-	dict  *Dict
-	log   *zap.SugaredLogger
-	exp   imcache.Expiration
+	games    *imcache.Cache[ChatKey, *gameConfig]
+	db       *DB
+	wdb      *WordDB
+	packs    *ChatPacks
+	dict     *Dict
+	diff     *Difficulty
+	daily    *Daily
+	profiles *Profiles
+	ratings  *Ratings
+	// onUnlock announces achievements unlocked in the chat
+	onUnlock func(ChatKey, []unlockedAchievement)
+	log      *zap.SugaredLogger
+	exp      imcache.Expiration
 }
 
-func NewGame(db *DB, wdb *WordDB, dict *Dict, exp time.Duration) *Game {
+func NewGame(db *DB, wdb *WordDB, packs *ChatPacks, dict *Dict, daily *Daily, ratings *Ratings,
+	exp time.Duration) *Game {
 	if exp < time.Hour {
 		exp = time.Hour
 	}
 
-	return &Game{
-		db:   db,
-		wdb:  wdb,
-		dict: dict,
-		log:  zap.L().Named("game").Sugar(),
-		exp:  imcache.WithSlidingExpiration(exp),
+	g := &Game{
+		db:       db,
+		wdb:      wdb,
+		packs:    packs,
+		dict:     dict,
+		diff:     NewDifficulty(db),
+		daily:    daily,
+		profiles: NewProfiles(db),
+		ratings:  ratings,
+		log:      zap.L().Named("game").Sugar(),
+		exp:      imcache.WithSlidingExpiration(exp),
+	}
+
+	g.games = imcache.New[ChatKey, *gameConfig](
+		imcache.WithEvictionCallbackOption(g.onGameEvicted),
+		imcache.WithCleanerOption[ChatKey, *gameConfig](exp/4),
+	)
+
+	return g
+}
+
+func (g *Game) onGameEvicted(key ChatKey, gc *gameConfig, reason imcache.EvictionReason) {
+	if reason == imcache.EvictionReasonExpired && gc.isActive() {
+		g.addOutcome(key, gc, wordTimedOut, 0)
+	}
+}
+
+// OnAchievements sets the handler of achievements unlocked in the chat. It must be set before games start.
+func (g *Game) OnAchievements(handler func(ChatKey, []unlockedAchievement)) {
+	g.onUnlock = handler
+}
+
+// addOutcome records the outcome of the round. The guesser ID is zero unless a player guessed the word.
+func (g *Game) addOutcome(key ChatKey, gc *gameConfig, result wordResult, guesserID int64) {
+	outcome := wordOutcome{
+		result: result,
+		dur:    time.Since(gc.startedAt),
+	}
+
+	// in private chats the AI guesses the word
+	if key.IsPrivate() {
+		outcome.aiTurns = gc.guesses
+	}
+
+	difficulty := g.diff.AddOutcome(key, gc.pack, gc.word.Text, outcome)
+	g.finishDaily(gc, result == wordGuessed)
+
+	ev := &roundEvent{
+		wordOutcome: outcome,
+		hostID:      gc.hostID,
+		langID:      gc.pack.GetLangID(),
+		packID:      gc.pack.GetPackID(),
+		level:       g.ratings.GetLevel(gc.pack.GetLangID(), gc.pack.GetPackID()),
+		daily:       gc.daily != nil,
+	}
+	// only rounds of group chats are rated
+	if !key.IsPrivate() {
+		ev.guesserID = guesserID
+		g.ratings.AddRound(key.ChatID, ev, difficulty)
+	}
+
+	unlocked := g.profiles.AddRound(ev)
+	if len(unlocked) > 0 && g.onUnlock != nil {
+		g.onUnlock(key, unlocked)
+	}
+}
+
+func (g *Game) finishDaily(gc *gameConfig, solved bool) {
+	if gc.daily != nil {
+		g.daily.Finish(gc.hostID, gc.pack.GetLangID(), gc.daily.day, gc.guesses, solved)
 	}
 }
 
-func (g *Game) setWord(gc *gameConfig) {
-	gc.word = gc.pack.GetWord()
-	def, hasDef := g.dict.FindDefinition(gc.pack.GetLangID(), gc.pack.GetPart(), gc.word)
-	if hasDef {
-		gc.def = def
+func (g *Game) setWord(key ChatKey, gc *gameConfig) {
+	g.startRound(key, gc, g.diff.GetWord(key, gc.pack))
+}
+
+func (g *Game) startRound(key ChatKey, gc *gameConfig, word Word) {
+	gc.word = word
+	gc.startedAt = time.Now()
+	gc.guesses = 0
+	gc.def = gc.word.Definition
+	hasDef := gc.def != ""
+	if !hasDef {
+		var def string
+		def, _, hasDef = g.dict.FindDefinition(gc.pack.GetLangID(), gc.pack.GetPart(), gc.word.Text)
+		if hasDef {
+			gc.def = def
+		}
+	}
+
+	gc.tabooMode = g.db.LoadChatConfig(key).Taboo
+	gc.taboo = nil
+	if gc.tabooMode {
+		gc.taboo = getTabooWords(gc.word, gc.def, gc.pack.GetLangID())
 	}
 
 	g.log.Infow("new word",
-		"word", gc.word,
+		"word", gc.word.Text,
 		"has_def", hasDef,
+		"taboo", len(gc.taboo),
 		"user_id", gc.hostID)
 }
 
-func (g *Game) createConfig(chatID int64) (*gameConfig, bool) {
-	gameConf, ok := g.games.Get(chatID)
+func (g *Game) getWordPack(key ChatKey, langID, packID string) (*WordPack, bool) {
+	if isChatPackID(packID) {
+		return g.packs.GetWordPack(key.ChatID, packID)
+	}
+
+	return g.wdb.GetWordPack(langID, packID)
+}
+
+func (g *Game) createConfig(key ChatKey) (*gameConfig, bool) {
+	gameConf, ok := g.games.Get(key)
 	if ok {
 		return gameConf, true
 	}
 
-	chatConf := g.db.LoadChatConfig(chatID)
-	pack, ok := g.wdb.GetWordPack(chatConf.LangID, chatConf.PackID)
+	chatConf := g.db.LoadChatConfig(key)
+	pack, ok := g.getWordPack(key, chatConf.LangID, chatConf.PackID)
 	if !ok {
 		return nil, false
 	}
@@ -100,66 +225,109 @@ func (g *Game) createConfig(chatID int64) (*gameConfig, bool) {
 		pack: pack,
 	}
 
-	g.games.Set(chatID, gameConf, g.exp)
+	g.games.Set(key, gameConf, g.exp)
 
 	return gameConf, true
 }
 
-func (g *Game) Play(chatID, hostID int64) (string, bool, bool) {
-	gameConf, ok := g.createConfig(chatID)
+func (g *Game) Play(key ChatKey, hostID int64) (string, bool, bool) {
+	gameConf, ok := g.createConfig(key)
 	if !ok || gameConf.isActive() {
 		return "", false, false
 	}
 
 	gameConf.hostID = hostID
-	g.setWord(gameConf)
+	g.setWord(key, gameConf)
 
 	g.log.Infow("game started",
-		"chat_id", chatID,
+		"chat_id", key.ChatID,
+		"thread_id", key.ThreadID,
 		"user_id", hostID,
 		"lang_id", gameConf.pack.GetLangID(),
 		"pack_id", gameConf.pack.GetPackID())
 
-	return gameConf.word, gameConf.hasDefinition(), true
+	return gameConf.word.Text, gameConf.hasDefinition(), true
 }
 
-func (g *Game) SetWordPack(chatID, playerID int64, langID, packID string) (string, bool, bool) {
-	gameConf, ok := g.games.Get(chatID)
+// PlayDaily starts the daily challenge with the word of the day. The word pack of the chat is restored
+// when the challenge ends.
+func (g *Game) PlayDaily(key ChatKey, hostID int64, word Word, pack *WordPack, day int) (bool, bool) {
+	gameConf, ok := g.createConfig(key)
+	if !ok || gameConf.isActive() {
+		return false, false
+	}
+
+	gameConf.daily = &dailyRound{day: day, prevPack: gameConf.pack}
+	gameConf.pack = pack
+	gameConf.hostID = hostID
+	g.startRound(key, gameConf, word)
+
+	g.log.Infow("daily challenge started",
+		"chat_id", key.ChatID,
+		"thread_id", key.ThreadID,
+		"user_id", hostID,
+		"lang_id", pack.GetLangID(),
+		"day", day)
+
+	return gameConf.hasDefinition(), true
+}
+
+// GetDaily returns the language and the day of the active daily challenge.
+func (g *Game) GetDaily(key ChatKey) (string, int, bool) {
+	gameConf, ok := g.games.Get(key)
+	if !ok || !gameConf.isActive() || gameConf.daily == nil {
+		return "", 0, false
+	}
+
+	return gameConf.pack.GetLangID(), gameConf.daily.day, true
+}
+
+func (g *Game) SetWordPack(key ChatKey, playerID int64, langID, packID string) (string, bool, bool) {
+	gameConf, ok := g.games.Get(key)
 	if !ok {
 		return "", false, true
 	}
 
 	if gameConf.isActive() && gameConf.hostID != playerID {
 		g.log.Warnw("player is not a host of this game",
-			"chat_id", chatID,
+			"chat_id", key.ChatID,
+			"thread_id", key.ThreadID,
 			"user_id", playerID)
 		return "", false, false
 	}
 
-	pack, ok := g.wdb.GetWordPack(langID, packID)
+	pack, ok := g.getWordPack(key, langID, packID)
 	if !ok {
 		return "", false, false
 	}
 
-	gameConf.pack = pack
-
-	if gameConf.isActive() {
-		g.setWord(gameConf)
+	var word string
+	switch {
+	case gameConf.daily != nil:
+		// the word of the day is kept, the pack is used after the challenge
+		gameConf.daily.prevPack = pack
+	case gameConf.isActive():
+		gameConf.pack = pack
+		g.setWord(key, gameConf)
+		word = gameConf.word.Text
+	default:
+		gameConf.pack = pack
 	}
 
-	g.games.Set(chatID, gameConf, g.exp)
+	g.games.Set(key, gameConf, g.exp)
 
 	g.log.Infow("word pack changed",
-		"chat_id", chatID,
+		"chat_id", key.ChatID,
+		"thread_id", key.ThreadID,
 		"user_id", playerID,
 		"lang_id", langID,
 		"pack_id", packID)
 
-	return gameConf.word, gameConf.hasDefinition(), true
+	return word, word != "" && gameConf.hasDefinition(), true
 }
 
-func (g *Game) Stop(chatID, playerID int64) bool {
-	gameConf, ok := g.games.Get(chatID)
+func (g *Game) Stop(key ChatKey, playerID int64) bool {
+	gameConf, ok := g.games.Get(key)
 	if !ok || !gameConf.isActive() {
 		return true
 	}
@@ -168,40 +336,54 @@ func (g *Game) Stop(chatID, playerID int64) bool {
 		return false
 	}
 
+	// the stopped round tells nothing about the word, so only the daily word is finished
+	g.finishDaily(gameConf, false)
 	gameConf.setNotActive()
 
-	g.games.Set(chatID, gameConf, g.exp)
+	// the next game starts with the current word pack of the chat, which could be reloaded or edited
+	g.games.Remove(key)
 
 	g.log.Infow("game stopped",
-		"chat_id", chatID,
+		"chat_id", key.ChatID,
+		"thread_id", key.ThreadID,
 		"user_id", playerID)
 
 	return true
 }
 
-func (g *Game) CheckGuess(chatID, playerID int64, guess string) (string, bool, bool) {
-	gameConf, ok := g.games.Get(chatID)
+// CheckGuess ends the round if the player guessed the word. It returns the word and the pack it was taken from.
+func (g *Game) CheckGuess(key ChatKey, playerID int64, guess string) (string, *WordPack, bool, bool) {
+	gameConf, ok := g.games.Get(key)
 	if !ok {
-		return "", false, false
+		return "", nil, false, false
+	}
+
+	if gameConf.isActive() && gameConf.hostID != playerID {
+		gameConf.guesses++
 	}
 
 	if !gameConf.checkGuess(playerID, guess) {
-		return "", false, false
+		return "", nil, false, false
 	}
 
-	word := gameConf.word
+	g.addOutcome(key, gameConf, wordGuessed, playerID)
+
+	word := gameConf.word.Text
+	// the pack of the chat is restored when the daily challenge ends
+	pack := gameConf.pack
 	hasDef := gameConf.hasDefinition()
 	gameConf.setNotActive()
 
 	g.log.Infow("word guessed",
-		"chat_id", chatID,
+		"chat_id", key.ChatID,
+		"thread_id", key.ThreadID,
 		"user_id", playerID)
 
-	return word, hasDef, true
+	return word, pack, hasDef, true
 }
 
-func (g *Game) SkipWord(chatID, playerID int64) (string, bool, bool) {
-	gameConf, ok := g.games.Get(chatID)
+func (g *Game) SkipWord(key ChatKey, playerID int64) (string, bool, bool) {
+	gameConf, ok := g.games.Get(key)
 	if !ok {
 		return "", false, false
 	}
@@ -210,17 +392,18 @@ func (g *Game) SkipWord(chatID, playerID int64) (string, bool, bool) {
 		return "", false, false
 	}
 
-	if gameConf.hostID != playerID {
+	if gameConf.hostID != playerID || gameConf.daily != nil {
 		return "", false, false
 	}
 
-	g.setWord(gameConf)
+	g.addOutcome(key, gameConf, wordSkipped, 0)
+	g.setWord(key, gameConf)
 
-	return gameConf.word, gameConf.hasDefinition(), true
+	return gameConf.word.Text, gameConf.hasDefinition(), true
 }
 
-func (g *Game) GetWord(chatID, playerID int64) (string, bool) {
-	gameConf, ok := g.games.Get(chatID)
+func (g *Game) GetWord(key ChatKey, playerID int64) (string, bool) {
+	gameConf, ok := g.games.Get(key)
 	if !ok {
 		return "", false
 	}
@@ -233,11 +416,85 @@ func (g *Game) GetWord(chatID, playerID int64) (string, bool) {
 		return "", false
 	}
 
-	return gameConf.word, true
+	return gameConf.word.Text, true
+}
+
+// IsHost reports whether the player is the host of the active game.
+func (g *Game) IsHost(key ChatKey, playerID int64) bool {
+	gameConf, ok := g.games.Get(key)
+	return ok && gameConf.isActive() && gameConf.hostID == playerID
 }
 
-func (g *Game) GetDefinition(chatID, playerID int64) (string, bool) {
-	gameConf, ok := g.games.Get(chatID)
+// GetHostWord returns the current word with its pack if the player is the host.
+func (g *Game) GetHostWord(key ChatKey, playerID int64) (Word, *WordPack, bool) {
+	gameConf, ok := g.games.Get(key)
+	if !ok {
+		return Word{}, nil, false
+	}
+
+	if !gameConf.isActive() {
+		return Word{}, nil, false
+	}
+
+	if gameConf.hostID != playerID {
+		return Word{}, nil, false
+	}
+
+	return gameConf.word, gameConf.pack, true
+}
+
+// GetTabooWords returns the forbidden words of the current round if the player is the host.
+func (g *Game) GetTabooWords(key ChatKey, playerID int64) ([]string, bool) {
+	gameConf, ok := g.games.Get(key)
+	if !ok {
+		return nil, false
+	}
+
+	if !gameConf.isActive() || !gameConf.tabooMode {
+		return nil, false
+	}
+
+	if gameConf.hostID != playerID {
+		return nil, false
+	}
+
+	return gameConf.taboo, true
+}
+
+// CheckTaboo ends the round if the host message contains the word or one of the forbidden words.
+// It returns the word and the used forbidden word.
+func (g *Game) CheckTaboo(key ChatKey, playerID int64, text string) (string, string, bool) {
+	gameConf, ok := g.games.Get(key)
+	if !ok {
+		return "", "", false
+	}
+
+	if !gameConf.isActive() || !gameConf.tabooMode || gameConf.hostID != playerID {
+		return "", "", false
+	}
+
+	forbidden := append([]string{gameConf.word.Text}, gameConf.taboo...)
+	taboo, used := findTabooWord(text, forbidden)
+	if !used {
+		return "", "", false
+	}
+
+	// the round is lost as if the host skipped the word
+	word := gameConf.word.Text
+	g.addOutcome(key, gameConf, wordSkipped, 0)
+	gameConf.setNotActive()
+
+	g.log.Infow("forbidden word used",
+		"chat_id", key.ChatID,
+		"thread_id", key.ThreadID,
+		"user_id", playerID,
+		"taboo", taboo)
+
+	return word, taboo, true
+}
+
+func (g *Game) GetDefinition(key ChatKey, playerID int64) (string, bool) {
+	gameConf, ok := g.games.Get(key)
 	if !ok {
 		return "", false
 	}
@@ -257,20 +514,26 @@ func (g *Game) GetDefinition(chatID, playerID int64) (string, bool) {
 	return gameConf.def, true
 }
 
-func (g *Game) IsActive(chatID int64) bool {
-	gameConf, ok := g.games.Get(chatID)
+func (g *Game) IsActive(key ChatKey) bool {
+	gameConf, ok := g.games.Get(key)
 	return ok && gameConf.isActive()
 }
 
-func (g *Game) GetActiveGames() []int64 {
-	var chatIDs []int64
+// ResetDifficulty drops the word difficulties computed for the replaced word packs.
+func (g *Game) ResetDifficulty() {
+	g.diff.Reset()
+}
+
+// GetActiveGames returns keys of chats and forum topics with active games.
+func (g *Game) GetActiveGames() []ChatKey {
+	var keys []ChatKey
 
 	games := g.games.PeekAll()
-	for chatID, game := range games {
+	for key, game := range games {
 		if game.isActive() {
-			chatIDs = append(chatIDs, chatID)
+			keys = append(keys, key)
 		}
 	}
 
-	return chatIDs
+	return keys
 }
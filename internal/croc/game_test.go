@@ -0,0 +1,129 @@
+package croc
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestGameConfigCheckGuess(t *testing.T) {
+	gc := &gameConfig{
+		word:   Word{Text: "Cat", Answers: []string{"kitty"}},
+		hostID: 1,
+	}
+
+	tests := []struct {
+		playerID int64
+		guess    string
+		want     bool
+	}{
+		{playerID: 2, guess: "cat", want: true},
+		{playerID: 2, guess: "Cat!", want: true},
+		{playerID: 2, guess: "kitty", want: true},
+		{playerID: 2, guess: "dog", want: false},
+		{playerID: 2, guess: "cat cat cat", want: false},
+		{playerID: 1, guess: "cat", want: false},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, gc.checkGuess(tt.playerID, tt.guess), tt.guess)
+	}
+
+	gc.setNotActive()
+	require.False(t, gc.checkGuess(2, "cat"))
+}
+
+func TestPlayDaily(t *testing.T) {
+	db := setupTestDB(t)
+	wdb := setupTestWordDB(t)
+	db.cfg.LangID, db.cfg.PackID = "en", "pack1"
+	daily := NewDaily(db, wdb, Config{})
+	game := NewGame(db, wdb, nil, nil, daily, NewRatings(db, Config{}), time.Hour)
+	key := ChatKey{ChatID: 1}
+
+	pack := &WordPack{langID: "en", packID: "daily", words: []Word{{Text: "cat", Definition: "a pet"}}}
+	require.True(t, daily.Start(1, "Alice", "en", 100))
+	hasDef, ok := game.PlayDaily(key, 1, pack.words[0], pack, 100)
+	require.True(t, ok)
+	require.True(t, hasDef)
+
+	_, _, ok = game.SkipWord(key, 1)
+	require.False(t, ok)
+	_, day, ok := game.GetDaily(key)
+	require.True(t, ok)
+	require.Equal(t, 100, day)
+
+	_, _, _, guessed := game.CheckGuess(key, 2, "dog")
+	require.False(t, guessed)
+	word, wordPack, _, guessed := game.CheckGuess(key, 2, "cat")
+	require.True(t, guessed)
+	require.Equal(t, "cat", word)
+	require.Equal(t, "daily", wordPack.GetPackID())
+
+	_, _, ok = game.GetDaily(key)
+	require.False(t, ok)
+	result, ok := db.LoadDailyResult(100, "en", 1)
+	require.True(t, ok)
+	require.True(t, result.Solved)
+	require.Equal(t, 2, result.Turns)
+
+	// the word pack of the chat is restored
+	gc, _ := game.games.Get(key)
+	require.Equal(t, "pack1", gc.pack.GetPackID())
+}
+
+func TestCheckTaboo(t *testing.T) {
+	db := setupTestDB(t)
+	wdb := setupTestWordDB(t)
+	game := NewGame(db, wdb, nil, nil, NewDaily(db, wdb, Config{}), NewRatings(db, Config{}), time.Hour)
+	key := ChatKey{ChatID: -1}
+
+	pack, _ := wdb.GetWordPack("en", "pack1")
+	game.games.Set(key, &gameConfig{
+		pack:      pack,
+		word:      Word{Text: "word1"},
+		hostID:    1,
+		startedAt: time.Now(),
+		tabooMode: true,
+		taboo:     []string{"pet"},
+	}, game.exp)
+
+	_, _, broken := game.CheckTaboo(key, 2, "pets")
+	require.False(t, broken)
+	_, _, broken = game.CheckTaboo(key, 1, "a petrol")
+	require.False(t, broken)
+	word, taboo, broken := game.CheckTaboo(key, 1, "my pets")
+	require.True(t, broken)
+	require.Equal(t, "word1", word)
+	require.Equal(t, "pet", taboo)
+	require.False(t, game.IsActive(key))
+
+	// the lost round is recorded like a skipped word
+	stats := db.LoadWordStats("en", "pack1")
+	require.Len(t, stats, 1)
+	require.Equal(t, 1, stats[0].Skipped)
+	require.Equal(t, 1, db.LoadPlayerStat(1).Hosted)
+}
+
+func TestStop(t *testing.T) {
+	db := setupTestDB(t)
+	wdb := setupTestWordDB(t)
+	game := NewGame(db, wdb, nil, nil, NewDaily(db, wdb, Config{}), NewRatings(db, Config{}), time.Hour)
+	key := ChatKey{ChatID: -1}
+
+	pack, _ := wdb.GetWordPack("en", "pack1")
+	game.games.Set(key, &gameConfig{
+		pack:      pack,
+		word:      Word{Text: "word1"},
+		hostID:    1,
+		startedAt: time.Now(),
+	}, game.exp)
+
+	require.False(t, game.Stop(key, 2))
+	require.True(t, game.IsActive(key))
+	require.True(t, game.Stop(key, 1))
+	require.False(t, game.IsActive(key))
+
+	// the stopped round does not change the word difficulty
+	require.Empty(t, db.LoadWordStats("en", "pack1"))
+}
@@ -0,0 +1,292 @@
+package croc
+
+import (
+	"fmt"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+	"html"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	msgProfile = &i18n.Message{
+		ID: "msg_profile",
+		Other: "Profile of {{.name}}\n" +
+			"Rounds hosted: {{.hosted}}\n" +
+			"Words guessed: {{.guessed}}\n" +
+			"Best guess time: {{.best_time}}\n" +
+			"Favorite word pack: {{.pack}}\n" +
+			"Best single player game: {{.ai_turns}}",
+	}
+	msgProfileSeconds      = &i18n.Message{ID: "msg_profile_seconds", Other: "{{.seconds}} s"}
+	msgProfileAiTurns      = &i18n.Message{ID: "msg_profile_ai_turns", Other: "guessed by the AI in {{.turns}} turns"}
+	msgProfileAchievements = &i18n.Message{ID: "msg_profile_achievements", Other: "Achievements:"}
+	msgProfileNoAchieve    = &i18n.Message{ID: "msg_profile_no_achievements", Other: "No achievements yet."}
+	msgPlayer              = &i18n.Message{ID: "msg_player", Other: "Player"}
+	msgAchievementUnlocked = &i18n.Message{
+		ID:    "msg_achievement_unlocked",
+		Other: "{{.name}} unlocked the achievement {{.achievement}}!",
+	}
+	achFirstGuess  = &i18n.Message{ID: "ach_first_guess", Other: "First guess"}
+	achGuessed100  = &i18n.Message{ID: "ach_guessed_100", Other: "100 words guessed"}
+	achQuickGuess  = &i18n.Message{ID: "ach_quick_guess", Other: "Guessed in 10 seconds"}
+	achC2Word      = &i18n.Message{ID: "ach_c2_word", Other: "Guessed a C2 word"}
+	achFirstHost   = &i18n.Message{ID: "ach_first_host", Other: "First round hosted"}
+	achHosted100   = &i18n.Message{ID: "ach_hosted_100", Other: "100 rounds hosted"}
+	achAiFirstTurn = &i18n.Message{ID: "ach_ai_first_turn", Other: "The AI guessed on the first turn"}
+	achStreak10    = &i18n.Message{ID: "ach_streak_10", Other: "10-day streak"}
+)
+
+// profileEmpty is shown instead of profile values the player has not reached yet.
+const profileEmpty = "—"
+
+// roundEvent is the outcome of the finished round for profiles of its players.
+type roundEvent struct {
+	wordOutcome
+	hostID int64
+	// guesserID is zero unless the word is guessed by a player in the group.
+	guesserID int64
+	langID    string
+	packID    string
+	// level is the level of the word pack set in the config, zero if it isn't set.
+	level int
+	daily bool
+}
+
+// achievement is unlocked by the host or the guesser of the round when its condition is met.
+type achievement struct {
+	id      string
+	icon    string
+	name    *i18n.Message
+	host    func(st *PlayerStat, ev *roundEvent) bool
+	guesser func(st *PlayerStat, ev *roundEvent) bool
+	// streak is checked when the host finishes the daily challenge
+	streak func(st *DailyStreak) bool
+}
+
+var achievements = []achievement{
+	{id: "first_guess", icon: "🎯", name: achFirstGuess, guesser: func(st *PlayerStat, ev *roundEvent) bool {
+		return st.Guessed >= 1
+	}},
+	{id: "guessed_100", icon: "💯", name: achGuessed100, guesser: func(st *PlayerStat, ev *roundEvent) bool {
+		return st.Guessed >= 100
+	}},
+	{id: "quick_guess", icon: "⚡", name: achQuickGuess, guesser: func(st *PlayerStat, ev *roundEvent) bool {
+		return ev.dur <= 10*time.Second
+	}},
+	{id: "c2_word", icon: "🎓", name: achC2Word, guesser: func(st *PlayerStat, ev *roundEvent) bool {
+		return ev.level == maxPackLevel
+	}},
+	{id: "first_host", icon: "🎤", name: achFirstHost, host: func(st *PlayerStat, ev *roundEvent) bool {
+		return st.Hosted >= 1
+	}},
+	{id: "hosted_100", icon: "🏆", name: achHosted100, host: func(st *PlayerStat, ev *roundEvent) bool {
+		return st.Hosted >= 100
+	}},
+	{id: "ai_first_turn", icon: "🤖", name: achAiFirstTurn, host: func(st *PlayerStat, ev *roundEvent) bool {
+		return ev.result == wordGuessed && ev.aiTurns == 1
+	}},
+	{id: "streak_10", icon: "🔥", name: achStreak10, streak: func(st *DailyStreak) bool {
+		return st.Current >= 10
+	}},
+}
+
+func findAchievement(id string) (*achievement, bool) {
+	for i := range achievements {
+		if achievements[i].id == id {
+			return &achievements[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// unlockedAchievement is the achievement just unlocked by the player.
+type unlockedAchievement struct {
+	userID      int64
+	achievement *achievement
+}
+
+// Profiles updates player statistics and unlocks achievements on game events.
+type Profiles struct {
+	db  *DB
+	mu  sync.Mutex
+	log *zap.SugaredLogger
+}
+
+func NewProfiles(db *DB) *Profiles {
+	return &Profiles{
+		db:  db,
+		log: zap.L().Named("profiles").Sugar(),
+	}
+}
+
+// AddRound updates statistics of the host and the guesser of the round and returns the unlocked achievements.
+func (p *Profiles) AddRound(ev *roundEvent) []unlockedAchievement {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var unlocked []unlockedAchievement
+
+	host := p.db.LoadPlayerStat(ev.hostID)
+	host.Hosted++
+	if ev.result == wordGuessed && ev.aiTurns > 0 && (host.AiBestTurns == 0 || ev.aiTurns < host.AiBestTurns) {
+		host.AiBestTurns = ev.aiTurns
+	}
+	p.db.SavePlayerStat(host)
+	p.addPackRound(ev.hostID, ev)
+	unlocked = p.unlock(unlocked, host, ev, func(a *achievement) bool {
+		return a.host != nil && a.host(host, ev)
+	})
+
+	if ev.daily {
+		st := p.db.LoadDailyStreak(ev.hostID, ev.langID)
+		unlocked = p.unlock(unlocked, host, ev, func(a *achievement) bool {
+			return a.streak != nil && a.streak(st)
+		})
+	}
+
+	if ev.guesserID != 0 {
+		guesser := p.db.LoadPlayerStat(ev.guesserID)
+		guesser.Guessed++
+		guessTime := ev.dur.Seconds()
+		if guesser.BestGuessTime == 0 || guessTime < guesser.BestGuessTime {
+			guesser.BestGuessTime = guessTime
+		}
+		p.db.SavePlayerStat(guesser)
+		p.addPackRound(ev.guesserID, ev)
+		unlocked = p.unlock(unlocked, guesser, ev, func(a *achievement) bool {
+			return a.guesser != nil && a.guesser(guesser, ev)
+		})
+	}
+
+	return unlocked
+}
+
+func (p *Profiles) addPackRound(userID int64, ev *roundEvent) {
+	if !isChatPackID(ev.packID) {
+		p.db.AddPlayerPackRound(userID, ev.langID, ev.packID)
+	}
+}
+
+// unlock adds the achievements matching the condition which the player has not unlocked yet.
+func (p *Profiles) unlock(unlocked []unlockedAchievement, st *PlayerStat, ev *roundEvent,
+	cond func(*achievement) bool) []unlockedAchievement {
+	for i := range achievements {
+		a := &achievements[i]
+		if !cond(a) || !p.db.AddAchievement(st.UserID, a.id) {
+			continue
+		}
+
+		p.log.Infow("achievement unlocked",
+			"user_id", st.UserID,
+			"achievement", a.id,
+			"lang_id", ev.langID,
+			"pack_id", ev.packID)
+		unlocked = append(unlocked, unlockedAchievement{userID: st.UserID, achievement: a})
+	}
+
+	return unlocked
+}
+
+// announceAchievements sends the unlocked achievements to the chat or the topic of the round.
+func (bot *Bot) announceAchievements(key ChatKey, unlocked []unlockedAchievement) {
+	locale := bot.getLocaleByKey(key)
+	chat := tele.ChatID(key.ChatID)
+	for _, ua := range unlocked {
+		// the game keeps only user IDs, names are requested when achievements are unlocked
+		name := fmt.Sprintf("<a href=\"tg://user?id=%d\">%s</a>", ua.userID, bot.tr(msgPlayer, locale))
+		member, err := bot.bot.ChatMemberOf(chat, &tele.User{ID: ua.userID})
+		if err == nil && member.User != nil {
+			name = printUserName(member.User)
+		}
+
+		lc := &i18n.LocalizeConfig{
+			DefaultMessage: msgAchievementUnlocked,
+			TemplateData: map[string]string{
+				"name":        name,
+				"achievement": ua.achievement.icon + " <b>" + bot.tr(ua.achievement.name, locale) + "</b>",
+			},
+		}
+		_, err = bot.bot.Send(chat, bot.trCfg(lc, locale), &tele.SendOptions{
+			ThreadID:  key.ThreadID,
+			ParseMode: tele.ModeHTML,
+		})
+		if err != nil {
+			bot.log.Warn(err)
+		}
+	}
+}
+
+func (bot *Bot) showProfile(c tele.Context) error {
+	locale := bot.getLocale(c)
+	user := c.Sender()
+	st := bot.db.LoadPlayerStat(user.ID)
+
+	bestTime := profileEmpty
+	if st.BestGuessTime > 0 {
+		lc := &i18n.LocalizeConfig{
+			DefaultMessage: msgProfileSeconds,
+			TemplateData:   map[string]string{"seconds": strconv.FormatFloat(st.BestGuessTime, 'f', 1, 64)},
+		}
+		bestTime = bot.trCfg(lc, locale)
+	}
+
+	aiTurns := profileEmpty
+	if st.AiBestTurns > 0 {
+		lc := &i18n.LocalizeConfig{
+			DefaultMessage: msgProfileAiTurns,
+			TemplateData:   map[string]string{"turns": strconv.Itoa(st.AiBestTurns)},
+		}
+		aiTurns = bot.trCfg(lc, locale)
+	}
+
+	packName := profileEmpty
+	if fav, ok := bot.db.LoadFavoritePack(user.ID); ok {
+		name, ok := bot.wdb.GetLocalWordPackName(fav.LangID, fav.PackID, locale)
+		if !ok {
+			name = fav.PackID
+		}
+		langName, ok := bot.wdb.GetLanguageName(fav.LangID)
+		if !ok {
+			langName = fav.LangID
+		}
+		packName = html.EscapeString(langName + ", " + name)
+	}
+
+	lc := &i18n.LocalizeConfig{
+		DefaultMessage: msgProfile,
+		TemplateData: map[string]string{
+			"name":      printUserName(user),
+			"hosted":    strconv.Itoa(st.Hosted),
+			"guessed":   strconv.Itoa(st.Guessed),
+			"best_time": bestTime,
+			"pack":      packName,
+			"ai_turns":  aiTurns,
+		},
+	}
+
+	var msg strings.Builder
+	msg.WriteString(bot.trCfg(lc, locale))
+	msg.WriteString("\n\n")
+
+	unlocked := bot.db.LoadAchievements(user.ID)
+	if len(unlocked) == 0 {
+		msg.WriteString(bot.tr(msgProfileNoAchieve, locale))
+	} else {
+		msg.WriteString(bot.tr(msgProfileAchievements, locale))
+	}
+	for _, pa := range unlocked {
+		a, ok := findAchievement(pa.AchievementID)
+		if !ok {
+			continue
+		}
+		msg.WriteString(fmt.Sprintf("\n%s %s", a.icon, bot.tr(a.name, locale)))
+	}
+
+	return c.Reply(msg.String(), tele.ModeHTML)
+}
@@ -0,0 +1,96 @@
+package croc
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func unlockedIDs(unlocked []unlockedAchievement) []string {
+	ids := make([]string, 0, len(unlocked))
+	for _, ua := range unlocked {
+		ids = append(ids, ua.achievement.id)
+	}
+	return ids
+}
+
+func TestProfilesAddRound(t *testing.T) {
+	db := setupTestDB(t)
+	profiles := NewProfiles(db)
+
+	unlocked := profiles.AddRound(&roundEvent{
+		wordOutcome: wordOutcome{result: wordGuessed, dur: 5 * time.Second},
+		hostID:      1,
+		guesserID:   2,
+		langID:      "en",
+		packID:      "C2",
+		level:       maxPackLevel,
+	})
+	require.ElementsMatch(t, []string{"first_host", "first_guess", "quick_guess", "c2_word"}, unlockedIDs(unlocked))
+
+	unlocked = profiles.AddRound(&roundEvent{
+		wordOutcome: wordOutcome{result: wordGuessed, dur: 30 * time.Second},
+		hostID:      1,
+		guesserID:   2,
+		langID:      "en",
+		packID:      "pack1",
+	})
+	require.Empty(t, unlocked)
+
+	profiles.AddRound(&roundEvent{
+		wordOutcome: wordOutcome{result: wordGuessed, dur: 20 * time.Second},
+		hostID:      2,
+		guesserID:   3,
+		langID:      "en",
+		packID:      "pack1",
+	})
+
+	st := db.LoadPlayerStat(2)
+	require.Equal(t, 1, st.Hosted)
+	require.Equal(t, 2, st.Guessed)
+	require.Equal(t, 5.0, st.BestGuessTime)
+
+	fav, ok := db.LoadFavoritePack(2)
+	require.True(t, ok)
+	require.Equal(t, "pack1", fav.PackID)
+	_, ok = db.LoadFavoritePack(4)
+	require.False(t, ok)
+
+	require.Len(t, db.LoadAchievements(1), 1)
+	require.Len(t, db.LoadAchievements(2), 4)
+}
+
+func TestProfilesAiAndStreak(t *testing.T) {
+	db := setupTestDB(t)
+	profiles := NewProfiles(db)
+
+	unlocked := profiles.AddRound(&roundEvent{
+		wordOutcome: wordOutcome{result: wordGuessed, aiTurns: 3},
+		hostID:      1,
+		langID:      "en",
+		packID:      "pack1",
+	})
+	require.Equal(t, []string{"first_host"}, unlockedIDs(unlocked))
+
+	unlocked = profiles.AddRound(&roundEvent{
+		wordOutcome: wordOutcome{result: wordGuessed, aiTurns: 1},
+		hostID:      1,
+		langID:      "en",
+		packID:      "pack1",
+	})
+	require.Equal(t, []string{"ai_first_turn"}, unlockedIDs(unlocked))
+	require.Equal(t, 1, db.LoadPlayerStat(1).AiBestTurns)
+
+	for day := 100; day < 110; day++ {
+		db.UpdateDailyStreak(1, "en", day, true)
+	}
+	unlocked = profiles.AddRound(&roundEvent{
+		wordOutcome: wordOutcome{result: wordGuessed, aiTurns: 2},
+		hostID:      1,
+		langID:      "en",
+		packID:      "pack1",
+		daily:       true,
+	})
+	require.Equal(t, []string{"streak_10"}, unlockedIDs(unlocked))
+	require.Equal(t, 1, db.LoadPlayerStat(1).AiBestTurns)
+}
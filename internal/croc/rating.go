@@ -0,0 +1,289 @@
+package croc
+
+import (
+	"fmt"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	msgRating = &i18n.Message{
+		ID:    "msg_rating",
+		Other: "Rating of season {{.season}}, it ends on {{.end}}.",
+	}
+	msgRatingArchive  = &i18n.Message{ID: "msg_rating_archive", Other: "Final rating of season {{.season}}."}
+	msgRatingNoSeason = &i18n.Message{
+		ID:    "msg_rating_no_season",
+		Other: "There is no such season. The current season is {{.season}}.",
+	}
+	msgRatingChat     = &i18n.Message{ID: "msg_rating_chat", Other: "This chat"}
+	msgRatingGlobal   = &i18n.Message{ID: "msg_rating_global", Other: "All chats"}
+	msgRatingGuessers = &i18n.Message{ID: "msg_rating_guessers", Other: "Guessers:"}
+	msgRatingHosts    = &i18n.Message{ID: "msg_rating_hosts", Other: "Hosts:"}
+	msgRatingEmpty    = &i18n.Message{ID: "msg_rating_empty", Other: "Nobody is rated yet."}
+)
+
+const (
+	initialRating = 1500
+	// ratingScale is the rating difference at which the stronger side is expected to score 10 times more.
+	ratingScale = 400
+	// wordRatingSpread is the rating difference between the easiest and the hardest words.
+	wordRatingSpread = 800
+	// maxPackLevel is the level of C2 word packs.
+	maxPackLevel = 6
+	// ratingTopSize is the number of guessers and hosts in the standings.
+	ratingTopSize = 5
+)
+
+// ratingEpoch is the start of the first season, seasons are numbered from it.
+var ratingEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ratingSeason returns the number of the season at t.
+func ratingSeason(t time.Time, months int) int {
+	t = t.UTC()
+	elapsed := (t.Year()-ratingEpoch.Year())*12 + int(t.Month()) - int(ratingEpoch.Month())
+	return elapsed/months + 1
+}
+
+// seasonEnd returns the start of the season following the given one.
+func seasonEnd(season, months int) time.Time {
+	return ratingEpoch.AddDate(0, season*months, 0)
+}
+
+// wordRating turns the word difficulty from 0 to 1 into the rating of the word as the opponent of players.
+func wordRating(difficulty float64) float64 {
+	return initialRating + wordRatingSpread*(difficulty-0.5)
+}
+
+// expectedScore returns the expected score of the player against the opponent, from 0 to 1.
+func expectedScore(rating, opponent float64) float64 {
+	return 1 / (1 + math.Pow(10, (opponent-rating)/ratingScale))
+}
+
+// updateRating moves the rating by the difference between the score and the expected score.
+func updateRating(rating, opponent, score, k float64) float64 {
+	return rating + k*(score-expectedScore(rating, opponent))
+}
+
+// Ratings keeps Elo ratings of guessers and hosts per season, in each chat and in all chats.
+// Players play against the word: guessing a hard word quickly raises the guesser rating,
+// and getting it guessed quickly raises the host rating.
+type Ratings struct {
+	db     *DB
+	mu     sync.Mutex
+	months int
+	k      float64
+	levels map[string]int
+	log    *zap.SugaredLogger
+}
+
+func NewRatings(db *DB, cfg Config) *Ratings {
+	r := &Ratings{
+		db:  db,
+		log: zap.L().Named("ratings").Sugar(),
+	}
+	r.SetConfig(cfg)
+
+	return r
+}
+
+// SetConfig replaces the season length, the K-factor and the levels of word packs.
+// Changing the season length renumbers the seasons.
+func (r *Ratings) SetConfig(cfg Config) {
+	levels := make(map[string]int)
+	for _, lang := range cfg.Languages {
+		for _, pack := range lang.WordPacks {
+			if pack.Level > 0 {
+				levels[lang.ID+"/"+pack.ID] = min(pack.Level, maxPackLevel)
+			}
+		}
+	}
+
+	months := cfg.Rating.SeasonMonths
+	if months <= 0 {
+		months = 3
+	}
+	k := cfg.Rating.KFactor
+	if k <= 0 {
+		k = 32
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.months = months
+	r.k = k
+	r.levels = levels
+}
+
+// GetSeason returns the season at t and the time it ends.
+func (r *Ratings) GetSeason(t time.Time) (int, time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	season := ratingSeason(t, r.months)
+	return season, seasonEnd(season, r.months)
+}
+
+// GetLevel returns the level of the word pack, or zero if it isn't set.
+func (r *Ratings) GetLevel(langID, packID string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.levels[langID+"/"+packID]
+}
+
+// wordDifficulty returns the difficulty of the word for ratings. The observed difficulty is averaged
+// with the level of the pack if it is known. The caller must hold the lock.
+func (r *Ratings) wordDifficulty(langID, packID string, observed float64) float64 {
+	level, ok := r.levels[langID+"/"+packID]
+	if !ok {
+		return observed
+	}
+
+	return (observed + float64(level)/(maxPackLevel+1)) / 2
+}
+
+// loadRating returns the rating of the player, new players start with the initial ratings.
+// The caller must hold the lock.
+func (r *Ratings) loadRating(season int, chatID, userID int64) *PlayerRating {
+	rating, ok := r.db.LoadPlayerRating(season, chatID, userID)
+	if !ok {
+		rating.Guesser = initialRating
+		rating.Host = initialRating
+	}
+
+	return rating
+}
+
+// AddRound updates ratings of the host and the guesser of the round in the chat and in all chats.
+// The observed difficulty is the difficulty of the word before the round. Rounds which timed out are not rated.
+func (r *Ratings) AddRound(chatID int64, ev *roundEvent, observed float64) {
+	if ev.result == wordTimedOut {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	season := ratingSeason(time.Now(), r.months)
+	opponent := wordRating(r.wordDifficulty(ev.langID, ev.packID, observed))
+	score := outcomePerformance(ev.wordOutcome)
+
+	for _, id := range []int64{chatID, 0} {
+		host := r.loadRating(season, id, ev.hostID)
+		host.Host = updateRating(host.Host, opponent, score, r.k)
+		host.Hosted++
+		r.db.SavePlayerRating(host)
+
+		if ev.guesserID != 0 {
+			guesser := r.loadRating(season, id, ev.guesserID)
+			guesser.Guesser = updateRating(guesser.Guesser, opponent, score, r.k)
+			guesser.Guessed++
+			r.db.SavePlayerRating(guesser)
+		}
+	}
+
+	r.log.Infow("ratings updated",
+		"chat_id", chatID,
+		"season", season,
+		"host_id", ev.hostID,
+		"guesser_id", ev.guesserID,
+		"word_rating", opponent,
+		"score", score)
+}
+
+// savePlayerName remembers the name of the player to show it in ratings of all chats.
+func (bot *Bot) savePlayerName(user *tele.User) {
+	bot.db.SetPlayerName(user.ID, strings.TrimSpace(user.FirstName+" "+user.LastName))
+}
+
+// writeStandings writes the top guessers and hosts of the season in the chat, or in all chats if it is zero.
+func (bot *Bot) writeStandings(msg *strings.Builder, title string, season int, chatID int64, locale string) {
+	guessers := bot.db.LoadTopGuessers(season, chatID, ratingTopSize)
+	hosts := bot.db.LoadTopHosts(season, chatID, ratingTopSize)
+
+	msg.WriteString(fmt.Sprintf("\n\n<b>%s</b>\n", html.EscapeString(title)))
+	if len(guessers) == 0 && len(hosts) == 0 {
+		msg.WriteString(bot.tr(msgRatingEmpty, locale))
+		return
+	}
+
+	ids := make([]int64, 0, len(guessers)+len(hosts))
+	for _, r := range guessers {
+		ids = append(ids, r.UserID)
+	}
+	for _, r := range hosts {
+		ids = append(ids, r.UserID)
+	}
+	names := bot.db.LoadPlayerNames(ids)
+
+	writeList := func(header *i18n.Message, ratings []PlayerRating, rating func(*PlayerRating) float64) {
+		if len(ratings) == 0 {
+			return
+		}
+
+		msg.WriteString(bot.tr(header, locale))
+		for i := range ratings {
+			name, ok := names[ratings[i].UserID]
+			if !ok {
+				name = bot.tr(msgPlayer, locale)
+			}
+			msg.WriteString(fmt.Sprintf("\n%d. %s — %.0f", i+1, html.EscapeString(name), rating(&ratings[i])))
+		}
+		msg.WriteString("\n")
+	}
+
+	writeList(msgRatingGuessers, guessers, func(r *PlayerRating) float64 { return r.Guesser })
+	writeList(msgRatingHosts, hosts, func(r *PlayerRating) float64 { return r.Host })
+}
+
+// showRating sends the standings of the current season, or of the past season passed as the argument.
+// Group chats see their own standings and the standings of all chats.
+func (bot *Bot) showRating(c tele.Context) error {
+	locale := bot.getLocale(c)
+	current, end := bot.ratings.GetSeason(time.Now())
+
+	season := current
+	if args := c.Args(); len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > current {
+			lc := &i18n.LocalizeConfig{
+				DefaultMessage: msgRatingNoSeason,
+				TemplateData:   map[string]string{"season": strconv.Itoa(current)},
+			}
+			return c.Send(bot.trCfg(lc, locale))
+		}
+		season = n
+	}
+
+	lc := &i18n.LocalizeConfig{
+		DefaultMessage: msgRatingArchive,
+		TemplateData:   map[string]string{"season": strconv.Itoa(season)},
+	}
+	if season == current {
+		lc = &i18n.LocalizeConfig{
+			DefaultMessage: msgRating,
+			TemplateData: map[string]string{
+				"season": strconv.Itoa(season),
+				"end":    end.AddDate(0, 0, -1).Format(time.DateOnly),
+			},
+		}
+	}
+
+	var msg strings.Builder
+	msg.WriteString(bot.trCfg(lc, locale))
+	if c.Chat().Type != tele.ChatPrivate {
+		bot.writeStandings(&msg, bot.tr(msgRatingChat, locale), season, c.Chat().ID, locale)
+	}
+	bot.writeStandings(&msg, bot.tr(msgRatingGlobal, locale), season, 0, locale)
+
+	return c.Send(strings.TrimSpace(msg.String()), tele.ModeHTML)
+}
@@ -0,0 +1,111 @@
+package croc
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestRatingSeason(t *testing.T) {
+	require.Equal(t, 1, ratingSeason(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 3))
+	require.Equal(t, 1, ratingSeason(time.Date(2024, 3, 31, 23, 0, 0, 0, time.UTC), 3))
+	require.Equal(t, 2, ratingSeason(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), 3))
+	require.Equal(t, 5, ratingSeason(time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC), 3))
+	require.Equal(t, 14, ratingSeason(time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC), 1))
+
+	require.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), seasonEnd(1, 3))
+	require.Equal(t, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), seasonEnd(5, 3))
+}
+
+func TestUpdateRating(t *testing.T) {
+	require.InDelta(t, 0.5, expectedScore(1500, 1500), 1e-9)
+	require.InDelta(t, 1516, updateRating(1500, 1500, 1, 32), 1e-9)
+
+	// the same performance against a harder word gains more
+	easy := updateRating(1500, wordRating(0.2), 0.8, 32)
+	hard := updateRating(1500, wordRating(0.8), 0.8, 32)
+	require.Greater(t, hard, easy)
+}
+
+func TestRatingsAddRound(t *testing.T) {
+	db := setupTestDB(t)
+	cfg := Config{
+		Languages: []LanguageConfig{{ID: "en", WordPacks: []WordPackConfig{{ID: "C2", Level: 6}}}},
+	}
+	ratings := NewRatings(db, cfg)
+	season, _ := ratings.GetSeason(time.Now())
+
+	ratings.AddRound(10, &roundEvent{
+		wordOutcome: wordOutcome{result: wordGuessed, dur: 5 * time.Second},
+		hostID:      1,
+		guesserID:   2,
+		langID:      "en",
+		packID:      "pack1",
+	}, 0.5)
+	// a slow guess of the easy word lowers the guesser rating
+	ratings.AddRound(20, &roundEvent{
+		wordOutcome: wordOutcome{result: wordGuessed, dur: 5 * time.Minute},
+		hostID:      3,
+		guesserID:   2,
+		langID:      "en",
+		packID:      "pack1",
+	}, 0.1)
+	// rounds which timed out are not rated
+	ratings.AddRound(10, &roundEvent{
+		wordOutcome: wordOutcome{result: wordTimedOut},
+		hostID:      1,
+		langID:      "en",
+		packID:      "pack1",
+	}, 0.5)
+
+	chat, ok := db.LoadPlayerRating(season, 10, 2)
+	require.True(t, ok)
+	require.Greater(t, chat.Guesser, float64(initialRating))
+	require.Equal(t, 1, chat.Guessed)
+
+	other, _ := db.LoadPlayerRating(season, 20, 2)
+	require.Less(t, other.Guesser, float64(initialRating))
+
+	global, _ := db.LoadPlayerRating(season, 0, 2)
+	require.Equal(t, 2, global.Guessed)
+	require.InDelta(t, chat.Guesser+other.Guesser-initialRating, global.Guesser, 5)
+
+	host, _ := db.LoadPlayerRating(season, 10, 1)
+	require.Equal(t, 1, host.Hosted)
+	require.Greater(t, host.Host, float64(initialRating))
+
+	// the pack level makes the word harder
+	ratings.AddRound(10, &roundEvent{
+		wordOutcome: wordOutcome{result: wordGuessed, dur: 5 * time.Second},
+		hostID:      1,
+		guesserID:   4,
+		langID:      "en",
+		packID:      "C2",
+	}, 0.5)
+	c2, _ := db.LoadPlayerRating(season, 10, 4)
+	require.Greater(t, c2.Guesser, chat.Guesser)
+
+	// a skipped word lowers the host rating
+	ratings.AddRound(10, &roundEvent{
+		wordOutcome: wordOutcome{result: wordSkipped},
+		hostID:      5,
+		langID:      "en",
+		packID:      "pack1",
+	}, 0.5)
+	skipped, _ := db.LoadPlayerRating(season, 10, 5)
+	require.Less(t, skipped.Host, float64(initialRating))
+
+	top := db.LoadTopGuessers(season, 10, ratingTopSize)
+	require.Len(t, top, 2)
+	require.EqualValues(t, 4, top[0].UserID)
+	hosts := db.LoadTopHosts(season, 0, ratingTopSize)
+	require.Len(t, hosts, 3)
+	require.EqualValues(t, 1, hosts[0].UserID)
+
+	// ratings of the next season start over, the past season is kept
+	_, ok = db.LoadPlayerRating(season+1, 10, 2)
+	require.False(t, ok)
+
+	db.SetPlayerName(2, "Bob")
+	require.Equal(t, map[int64]string{2: "Bob"}, db.LoadPlayerNames([]int64{2, 3}))
+}
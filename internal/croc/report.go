@@ -0,0 +1,413 @@
+package croc
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	tele "gopkg.in/telebot.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	btnReport           = &i18n.Message{ID: "btn_report", Other: "Report"}
+	btnReportObscure    = &i18n.Message{ID: "btn_report_obscure", Other: "Obscure word"}
+	btnReportMisspelled = &i18n.Message{ID: "btn_report_misspelled", Other: "Misspelled word"}
+	btnReportOffensive  = &i18n.Message{ID: "btn_report_offensive", Other: "Offensive word"}
+	btnReportDefinition = &i18n.Message{ID: "btn_report_definition", Other: "Wrong definition"}
+	btnCancel           = &i18n.Message{ID: "btn_cancel", Other: "Cancel"}
+	msgReportSent       = &i18n.Message{ID: "msg_report_sent", Other: "Thank you! The report has been sent."}
+)
+
+const (
+	reasonObscure    = "obscure"
+	reasonMisspelled = "misspelled"
+	reasonOffensive  = "offensive"
+	reasonDefinition = "definition"
+)
+
+const (
+	reportNew       = "new"
+	reportExcluded  = "excluded"
+	reportDismissed = "dismissed"
+)
+
+// maxReviewWords limits the number of reported words shown to admins at once.
+const maxReviewWords = 20
+
+func newReportMenu(st *botState, locale string) *tele.ReplyMarkup {
+	menu := &tele.ReplyMarkup{}
+	obscureBtn := menu.Data(st.tr(btnReportObscure, locale), "report_word", reasonObscure)
+	misspelledBtn := menu.Data(st.tr(btnReportMisspelled, locale), "report_word", reasonMisspelled)
+	offensiveBtn := menu.Data(st.tr(btnReportOffensive, locale), "report_word", reasonOffensive)
+	cancelBtn := menu.Data(st.tr(btnCancel, locale), "report_cancel")
+	menu.Inline(menu.Row(obscureBtn), menu.Row(misspelledBtn), menu.Row(offensiveBtn), menu.Row(cancelBtn))
+
+	return menu
+}
+
+func (bot *Bot) newReportDefMenu(locale, langID, part, packID string) *tele.ReplyMarkup {
+	menu := &tele.ReplyMarkup{}
+	defBtn := menu.Data(bot.tr(btnReportDefinition, locale), "report_def", langID, part, packID, reasonDefinition)
+	offensiveBtn := menu.Data(bot.tr(btnReportOffensive, locale), "report_def", langID, part, packID, reasonOffensive)
+	menu.Inline(menu.Row(defBtn), menu.Row(offensiveBtn))
+
+	return menu
+}
+
+func hasButton(menu *tele.ReplyMarkup, unique string) bool {
+	if menu == nil {
+		return false
+	}
+
+	for _, row := range menu.InlineKeyboard {
+		for _, btn := range row {
+			if btn.Data == "\f"+unique || strings.HasPrefix(btn.Data, "\f"+unique+"|") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (bot *Bot) addReport(c tele.Context, langID, packID, part, word, reason string) {
+	bot.db.AddReport(&Report{
+		ChatID: c.Chat().ID,
+		UserID: c.Sender().ID,
+		LangID: langID,
+		PackID: packID,
+		Part:   part,
+		Word:   word,
+		Reason: reason,
+		Status: reportNew,
+	})
+
+	bot.log.Infow("word reported",
+		"chat_id", c.Chat().ID,
+		"user_id", c.Sender().ID,
+		"lang_id", langID,
+		"pack_id", packID,
+		"word", word,
+		"reason", reason)
+}
+
+func (bot *Bot) showReportMenu(c tele.Context) error {
+	locale := bot.getLocale(c)
+	_, _, ok := bot.game.GetHostWord(getChatKey(c), c.Sender().ID)
+	if !ok {
+		return respondAlert(c, bot.tr(msgNotHost, locale))
+	}
+
+	err := c.Edit(bot.state.Load().reportMenus[locale])
+	if err != nil {
+		return err
+	}
+
+	return c.Respond()
+}
+
+func (bot *Bot) restoreWordMenu(c tele.Context, locale string) error {
+	_, hasDef := bot.game.GetDefinition(getChatKey(c), c.Sender().ID)
+	if hasDef {
+		return c.Edit(bot.state.Load().wordDefMenus[locale])
+	}
+	return c.Edit(bot.state.Load().wordMenus[locale])
+}
+
+// isWordReason reports whether the reason is one of those the host can choose for the word.
+func isWordReason(reason string) bool {
+	return reason == reasonObscure || reason == reasonMisspelled || reason == reasonOffensive
+}
+
+func (bot *Bot) reportWord(c tele.Context) error {
+	if !isWordReason(c.Data()) {
+		return c.Respond()
+	}
+
+	locale := bot.getLocale(c)
+	word, pack, ok := bot.game.GetHostWord(getChatKey(c), c.Sender().ID)
+	if !ok {
+		return respondAlert(c, bot.tr(msgNotHost, locale))
+	}
+
+	bot.addReport(c, pack.GetLangID(), pack.GetPackID(), pack.GetPart(), word.Text, c.Data())
+
+	err := bot.restoreWordMenu(c, locale)
+	if err != nil {
+		return err
+	}
+
+	return respondAlert(c, bot.tr(msgReportSent, locale))
+}
+
+func (bot *Bot) cancelReport(c tele.Context) error {
+	locale := bot.getLocale(c)
+	_, _, ok := bot.game.GetHostWord(getChatKey(c), c.Sender().ID)
+	if !ok {
+		return respondAlert(c, bot.tr(msgNotHost, locale))
+	}
+
+	err := bot.restoreWordMenu(c, locale)
+	if err != nil {
+		return err
+	}
+
+	return c.Respond()
+}
+
+// isReportedPack reports whether the word pack and the part of speech from callback data exist.
+// The pack ID is empty in buttons sent by older versions.
+func (bot *Bot) isReportedPack(chatID int64, langID, part, packID string) bool {
+	if isChatPackID(packID) {
+		_, ok := bot.packs.GetWordPack(chatID, packID)
+		if !ok {
+			return false
+		}
+	} else if packID != "" {
+		_, ok := bot.wdb.GetWordPack(langID, packID)
+		if !ok {
+			return false
+		}
+	}
+
+	packIDs, _ := bot.wdb.GetWordPackIDs(langID)
+	for _, id := range packIDs {
+		pack, ok := bot.wdb.GetWordPack(langID, id)
+		if ok && pack.GetPart() == part {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (bot *Bot) reportDefinition(c tele.Context) error {
+	args := c.Args()
+	if len(args) < 4 {
+		return c.Respond()
+	}
+
+	if args[3] != reasonDefinition && args[3] != reasonOffensive {
+		return c.Respond()
+	}
+
+	if !bot.isReportedPack(c.Chat().ID, args[0], args[1], args[2]) {
+		return c.Respond()
+	}
+
+	// the definition message starts with the word
+	word, _, _ := strings.Cut(c.Message().Text, "\n")
+	bot.addReport(c, args[0], args[2], args[1], word, args[3])
+
+	err := c.Edit(&tele.ReplyMarkup{})
+	if err != nil {
+		return err
+	}
+
+	return respondAlert(c, bot.tr(msgReportSent, bot.getLocale(c)))
+}
+
+type reportedWord struct {
+	report  Report
+	reasons map[string]int
+}
+
+func groupReports(reports []Report) []*reportedWord {
+	words := make([]*reportedWord, 0, len(reports))
+	index := make(map[string]*reportedWord, len(reports))
+	for _, report := range reports {
+		key := report.LangID + "/" + report.PackID + "/" + report.Word
+		rw, ok := index[key]
+		if !ok {
+			rw = &reportedWord{report: report, reasons: make(map[string]int)}
+			index[key] = rw
+			words = append(words, rw)
+		}
+		rw.reasons[report.Reason]++
+	}
+
+	return words
+}
+
+func (bot *Bot) getReportsMessage() (string, *tele.ReplyMarkup) {
+	words := groupReports(bot.db.LoadReports(reportNew))
+	if len(words) == 0 {
+		return "There are no new reports.", nil
+	}
+
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("Reported words: %d.\n\n", len(words)))
+
+	if len(words) > maxReviewWords {
+		words = words[:maxReviewWords]
+	}
+
+	menu := &tele.ReplyMarkup{}
+	rows := make([]tele.Row, 0, len(words))
+	for i, rw := range words {
+		reasons := make([]string, 0, len(rw.reasons))
+		for reason, cnt := range rw.reasons {
+			reasons = append(reasons, fmt.Sprintf("%s: %d", reason, cnt))
+		}
+		sort.Strings(reasons)
+
+		r := rw.report
+		msg.WriteString(fmt.Sprintf("%d. %s (%s/%s/%s) — %s\n",
+			i+1, r.Word, r.LangID, r.PackID, r.Part, strings.Join(reasons, ", ")))
+
+		id := strconv.FormatUint(uint64(r.ID), 10)
+		excludeBtn := menu.Data(fmt.Sprintf("%d. Exclude", i+1), "review_report", id, reportExcluded)
+		dismissBtn := menu.Data(fmt.Sprintf("%d. Dismiss", i+1), "review_report", id, reportDismissed)
+		rows = append(rows, menu.Row(excludeBtn, dismissBtn))
+	}
+	menu.Inline(rows...)
+
+	return msg.String(), menu
+}
+
+func (bot *Bot) showReports(c tele.Context) error {
+	if !bot.isAdmin(c.Sender().ID) {
+		return nil
+	}
+
+	msg, menu := bot.getReportsMessage()
+	if menu == nil {
+		return c.Send(msg)
+	}
+	return c.Send(msg, menu)
+}
+
+func (bot *Bot) reviewReport(c tele.Context) error {
+	if !bot.isAdmin(c.Sender().ID) {
+		return c.Respond()
+	}
+
+	args := c.Args()
+	if len(args) < 2 {
+		return c.Respond()
+	}
+
+	id, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		return c.Respond()
+	}
+
+	status := args[1]
+	if status != reportExcluded && status != reportDismissed {
+		return c.Respond()
+	}
+
+	report, ok := bot.db.LoadReport(uint(id))
+	if !ok {
+		return c.Respond()
+	}
+
+	cnt := bot.db.SetReportStatus(report.LangID, report.PackID, report.Word, status)
+	bot.log.Infow("reports reviewed",
+		"user_id", c.Sender().ID,
+		"word", report.Word,
+		"status", status,
+		"count", cnt)
+
+	msg, menu := bot.getReportsMessage()
+	if menu == nil {
+		err = c.Edit(msg)
+	} else {
+		err = c.Edit(msg, menu)
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.Respond(&tele.CallbackResponse{Text: fmt.Sprintf("Reports updated: %d.", cnt)})
+}
+
+// ExportReports writes all reports from the bot database as CSV. If excludeDir is not empty,
+// it also writes words excluded by admins into <excludeDir>/<lang>/<pack>.txt files.
+func ExportReports(cfgPath string, out io.Writer, excludeDir string) error {
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		return err
+	}
+
+	db, ok := LoadDatabase(cfg.DBPath, ChatConfig{})
+	if !ok {
+		return fmt.Errorf("can't load database %s", cfg.DBPath)
+	}
+
+	reports := db.LoadReports("")
+
+	w := csv.NewWriter(out)
+	_ = w.Write([]string{"id", "created_at", "chat_id", "user_id",
+		"lang_id", "pack_id", "part", "word", "reason", "status"})
+	for _, r := range reports {
+		_ = w.Write([]string{
+			strconv.FormatUint(uint64(r.ID), 10),
+			r.CreatedAt.Format(time.RFC3339),
+			strconv.FormatInt(r.ChatID, 10),
+			strconv.FormatInt(r.UserID, 10),
+			r.LangID, r.PackID, r.Part, r.Word, r.Reason, r.Status,
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+
+	if excludeDir == "" {
+		return nil
+	}
+
+	return writeExclusions(reports, excludeDir)
+}
+
+// isPathName reports whether the ID can be used as a file name without leaving the directory.
+func isPathName(id string) bool {
+	return id != "" && id != "." && id != ".." && !strings.ContainsAny(id, `/\`)
+}
+
+func writeExclusions(reports []Report, dir string) error {
+	excluded := make(map[string]map[string]bool)
+	for _, r := range reports {
+		if r.Status != reportExcluded || r.PackID == "" || isChatPackID(r.PackID) {
+			continue
+		}
+
+		// reports of unknown packs could be forged before their IDs were checked
+		if !isPathName(r.LangID) || !isPathName(r.PackID) {
+			continue
+		}
+
+		path := filepath.Join(dir, r.LangID, r.PackID+".txt")
+		if excluded[path] == nil {
+			excluded[path] = make(map[string]bool)
+		}
+		excluded[path][r.Word] = true
+	}
+
+	for path, words := range excluded {
+		list := make([]string, 0, len(words))
+		for word := range words {
+			list = append(list, word)
+		}
+		sort.Strings(list)
+
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(path, []byte(strings.Join(list, "\n")+"\n"), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package croc
+
+import (
+	"github.com/stretchr/testify/require"
+	tele "gopkg.in/telebot.v3"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHasButton(t *testing.T) {
+	menu := &tele.ReplyMarkup{
+		InlineKeyboard: [][]tele.InlineButton{
+			{{Data: "\fsee_word"}},
+			{{Data: "\fsee_def_old|en"}},
+			{{Data: "\freport_word|obscure"}},
+		},
+	}
+
+	require.True(t, hasButton(menu, "see_word"))
+	require.True(t, hasButton(menu, "report_word"))
+	require.False(t, hasButton(menu, "see_def"))
+	require.False(t, hasButton(nil, "see_word"))
+}
+
+func TestIsWordReason(t *testing.T) {
+	require.True(t, isWordReason(reasonObscure))
+	require.True(t, isWordReason(reasonMisspelled))
+	require.True(t, isWordReason(reasonOffensive))
+	require.False(t, isWordReason(reasonDefinition))
+	require.False(t, isWordReason(""))
+	require.False(t, isWordReason("spam"))
+}
+
+func TestReports(t *testing.T) {
+	db := setupTestDB(t)
+
+	reports := []Report{
+		{LangID: "en", PackID: "A1", Word: "cat", Reason: reasonObscure},
+		{LangID: "en", PackID: "A1", Word: "dog", Reason: reasonOffensive},
+		{LangID: "en", PackID: "A1", Word: "cat", Reason: reasonObscure},
+		{LangID: "en", PackID: "A1", Word: "cat", Reason: reasonMisspelled},
+		{LangID: "en", PackID: "chat_1", Word: "cow", Reason: reasonOffensive},
+		{LangID: "..", PackID: "../../evil", Word: "pig", Reason: reasonDefinition},
+	}
+	for i := range reports {
+		reports[i].Status = reportNew
+		db.AddReport(&reports[i])
+	}
+
+	words := groupReports(db.LoadReports(reportNew))
+	require.Len(t, words, 4)
+	require.Equal(t, "cat", words[0].report.Word)
+	require.Equal(t, map[string]int{reasonObscure: 2, reasonMisspelled: 1}, words[0].reasons)
+
+	report, ok := db.LoadReport(reports[1].ID)
+	require.True(t, ok)
+	require.Equal(t, "dog", report.Word)
+
+	require.EqualValues(t, 3, db.SetReportStatus("en", "A1", "cat", reportExcluded))
+	require.EqualValues(t, 1, db.SetReportStatus("en", "chat_1", "cow", reportExcluded))
+	require.EqualValues(t, 1, db.SetReportStatus("en", "A1", "dog", reportDismissed))
+	require.EqualValues(t, 1, db.SetReportStatus("..", "../../evil", "pig", reportExcluded))
+	require.Empty(t, db.LoadReports(reportNew))
+
+	dir := t.TempDir()
+	err := writeExclusions(db.LoadReports(""), dir)
+	require.NoError(t, err)
+
+	excluded, err := ReadWords(filepath.Join(dir, "en", "A1.txt"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"cat"}, excluded)
+
+	_, err = os.Stat(filepath.Join(dir, "en", "chat_1.txt"))
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "..", "..", "evil.txt"))
+	require.True(t, os.IsNotExist(err))
+
+	require.True(t, isPathName("A1"))
+	require.False(t, isPathName(".."))
+	require.False(t, isPathName("en/A1"))
+	require.False(t, isPathName(`en\A1`))
+}
@@ -0,0 +1,194 @@
+package croc
+
+import (
+	"crocodiler/internal/wordmatch"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	tele "gopkg.in/telebot.v3"
+	"html"
+	"strings"
+	"unicode"
+)
+
+var (
+	msgTabooWords = &i18n.Message{ID: "msg_taboo_words", Other: "Forbidden words: {{.words}}."}
+	msgTabooOn    = &i18n.Message{
+		ID:    "msg_taboo_on",
+		Other: "Taboo mode is on. The host must not use the word and the forbidden words shown with it.",
+	}
+	msgTabooOff       = &i18n.Message{ID: "msg_taboo_off", Other: "Taboo mode is off."}
+	msgTabooViolation = &i18n.Message{
+		ID:    "msg_taboo_violation",
+		Other: "{{.name}} used the forbidden word <b>{{.taboo}}</b>. The word was <b>{{.word}}</b>.",
+	}
+)
+
+const (
+	minTabooWords = 3
+	maxTabooWords = 5
+)
+
+// tabooStopWords are frequent words not worth forbidding, only words of wordmatch.MinStem runes and longer.
+var tabooStopWords = map[string]map[string]bool{
+	"en": toSet("about", "also", "another", "being", "that", "their", "them", "there", "these", "they",
+		"thing", "this", "those", "used", "using", "which", "while", "with", "from", "have", "into",
+		"other", "some", "something", "someone", "such", "than", "very", "when", "where", "would",
+		"usually", "especially", "often", "person", "kind", "part", "make", "made"),
+	"ru": toSet("быть", "было", "были", "который", "которая", "которое", "которые", "также", "такой",
+		"такая", "такое", "такие", "этот", "того", "чего", "кого", "после", "перед", "между", "через",
+		"очень", "обычно", "часто", "нечто", "некто", "человек", "чтобы", "свой", "своя", "своё", "своих"),
+}
+
+func toSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
+
+func splitWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+}
+
+// findTabooWord returns the forbidden word used in the text. A forbidden phrase is used
+// if all its words are.
+func findTabooWord(text string, taboo []string) (string, bool) {
+	words := splitWords(text)
+	for _, phrase := range taboo {
+		parts := splitWords(phrase)
+		if len(parts) == 0 {
+			continue
+		}
+
+		used := true
+		for _, part := range parts {
+			found := false
+			for _, word := range words {
+				if wordmatch.Matches(word, part) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				used = false
+				break
+			}
+		}
+
+		if used {
+			return phrase, true
+		}
+	}
+
+	return "", false
+}
+
+// getTabooWords returns forbidden words from the word metadata, complemented
+// with content words of the definition if there are too few of them.
+func getTabooWords(word Word, def, langID string) []string {
+	taboo := make([]string, 0, maxTabooWords)
+	for _, t := range word.Taboo {
+		if len(taboo) == maxTabooWords {
+			return taboo
+		}
+		taboo = append(taboo, t)
+	}
+
+	if len(taboo) >= minTabooWords {
+		return taboo
+	}
+
+	isKnown := func(candidate string) bool {
+		known := append([]string{word.Text}, taboo...)
+		for _, k := range known {
+			for _, part := range splitWords(k) {
+				if wordmatch.Matches(candidate, part) || wordmatch.Matches(part, candidate) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	stopWords := tabooStopWords[langID]
+	for _, candidate := range splitWords(def) {
+		if len(taboo) == maxTabooWords {
+			break
+		}
+
+		if len([]rune(candidate)) < wordmatch.MinStem || stopWords[candidate] || isKnown(candidate) {
+			continue
+		}
+
+		if strings.Trim(candidate, "-0123456789") != candidate {
+			continue
+		}
+
+		taboo = append(taboo, candidate)
+	}
+
+	return taboo
+}
+
+func (bot *Bot) toggleTaboo(c tele.Context) error {
+	cfg := bot.db.LoadChatConfig(getChatKey(c))
+	taboo := !cfg.Taboo
+	bot.db.SetTaboo(getChatKey(c), taboo)
+
+	bot.log.Infow("taboo mode changed",
+		"chat_id", c.Chat().ID,
+		"user_id", c.Sender().ID,
+		"taboo", taboo)
+
+	if taboo {
+		return c.Send(bot.tr(msgTabooOn, cfg.Locale))
+	}
+	return c.Send(bot.tr(msgTabooOff, cfg.Locale))
+}
+
+// addTabooWords appends forbidden words of the current round to the text for the host.
+func (bot *Bot) addTabooWords(c tele.Context, text, locale string) string {
+	taboo, ok := bot.game.GetTabooWords(getChatKey(c), c.Sender().ID)
+	if !ok || len(taboo) == 0 {
+		return text
+	}
+
+	lc := &i18n.LocalizeConfig{
+		DefaultMessage: msgTabooWords,
+		TemplateData: map[string]string{
+			"words": strings.Join(taboo, ", "),
+		},
+	}
+
+	return text + "\n" + bot.trCfg(lc, locale)
+}
+
+// checkTaboo ends the round if the host used a forbidden word.
+func (bot *Bot) checkTaboo(c tele.Context) (bool, error) {
+	word, taboo, broken := bot.game.CheckTaboo(getChatKey(c), c.Sender().ID, c.Text())
+	if !broken {
+		return false, nil
+	}
+
+	if c.Chat().Type == tele.ChatPrivate {
+		bot.ai.StopChat(getChatKey(c))
+	}
+
+	locale := bot.getLocale(c)
+	lc := &i18n.LocalizeConfig{
+		DefaultMessage: msgTabooViolation,
+		TemplateData: map[string]string{
+			"name":  printUserName(c.Sender()),
+			"taboo": html.EscapeString(taboo),
+			"word":  word,
+		},
+	}
+
+	hostMenu := &tele.ReplyMarkup{}
+	hostBtn := hostMenu.Data(bot.tr(btnBecomeHost, locale), "become_host")
+	hostMenu.Inline(hostMenu.Row(hostBtn))
+
+	return true, c.Send(bot.trCfg(lc, locale), hostMenu, tele.ModeHTML)
+}
@@ -0,0 +1,44 @@
+package croc
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestFindTabooWord(t *testing.T) {
+	taboo := []string{"bark", "ice cream"}
+
+	_, ok := findTabooWord("It likes to play with a ball", taboo)
+	require.False(t, ok)
+
+	word, ok := findTabooWord("It BARKS at night!", taboo)
+	require.True(t, ok)
+	require.Equal(t, "bark", word)
+
+	_, ok = findTabooWord("It is cold as ice", taboo)
+	require.False(t, ok)
+
+	word, ok = findTabooWord("A cold cream made of ice", taboo)
+	require.True(t, ok)
+	require.Equal(t, "ice cream", word)
+
+	_, ok = findTabooWord("I like catch, cattle, petrol, carpet, career and petty", []string{"cat", "pet", "car"})
+	require.False(t, ok)
+
+	word, ok = findTabooWord("Two cars", []string{"cat", "pet", "car"})
+	require.True(t, ok)
+	require.Equal(t, "car", word)
+}
+
+func TestGetTabooWords(t *testing.T) {
+	word := Word{Text: "dog", Taboo: []string{"bark", "pet", "puppy", "leash", "bone", "cat"}}
+	require.Equal(t, []string{"bark", "pet", "puppy", "leash", "bone"}, getTabooWords(word, "", "en"))
+
+	word = Word{Text: "dog", Taboo: []string{"bark"}}
+	def := "A domesticated carnivorous mammal, which is kept as a pet; dogs bark with a barking sound."
+	require.Equal(t, []string{"bark", "domesticated", "carnivorous", "mammal", "kept"},
+		getTabooWords(word, def, "en"))
+
+	word = Word{Text: "dog"}
+	require.Empty(t, getTabooWords(word, "", "en"))
+}
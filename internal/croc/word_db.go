@@ -1,25 +1,65 @@
 package croc
 
 import (
-	"bufio"
 	"go.uber.org/zap"
 	"math/rand"
-	"os"
-	"strings"
+	"sync"
 )
 
 type WordPack struct {
-	langID string
-	packID string
-	part   string
-	words  []string
+	langID       string
+	packID       string
+	part         string
+	names        map[string]string
+	descriptions map[string]string
+	words        []Word
 }
 
-func (pack *WordPack) GetWord() string {
+func (pack *WordPack) GetWord() Word {
 	i := rand.Intn(len(pack.words))
 	return pack.words[i]
 }
 
+// GetSeededWord returns the word picked by the seed, the same seed picks the same word of the pack.
+func (pack *WordPack) GetSeededWord(seed uint64) Word {
+	return pack.words[seed%uint64(len(pack.words))]
+}
+
+// GetWeightedWord returns a random word with the probability proportional to its weight.
+func (pack *WordPack) GetWeightedWord(weight func(Word) float64) Word {
+	weights := make([]float64, len(pack.words))
+	var total float64
+	for i, word := range pack.words {
+		weights[i] = weight(word)
+		total += weights[i]
+	}
+
+	if total <= 0 {
+		return pack.GetWord()
+	}
+
+	r := rand.Float64() * total
+	for i, w := range weights {
+		r -= w
+		if r < 0 {
+			return pack.words[i]
+		}
+	}
+
+	return pack.words[len(pack.words)-1]
+}
+
+// FindWord returns the entry of the pack word with metadata.
+func (pack *WordPack) FindWord(text string) (Word, bool) {
+	for _, word := range pack.words {
+		if word.Text == text {
+			return word, true
+		}
+	}
+
+	return Word{}, false
+}
+
 func (pack *WordPack) GetLangID() string {
 	return pack.langID
 }
@@ -32,49 +72,43 @@ func (pack *WordPack) GetPart() string {
 	return pack.part
 }
 
-func (db *WordDB) loadWordPackImp(langID, packID, path, part string) (*WordPack, bool) {
-	file, err := os.Open(path)
-	if err != nil {
-		db.log.Error(err)
-		return nil, false
-	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			db.log.Warn(err)
-		}
-	}(file)
-
-	pack := &WordPack{
-		langID: langID,
-		packID: packID,
-		part:   part,
-		words:  make([]string, 0, 200),
-	}
+// GetName returns the pack name in the locale if the pack file provides it.
+func (pack *WordPack) GetName(locale string) (string, bool) {
+	name, ok := pack.names[locale]
+	return name, ok
+}
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		word := strings.TrimSpace(scanner.Text())
-		if word != "" {
-			pack.words = append(pack.words, word)
-		}
-	}
+func (pack *WordPack) GetDescription(locale string) string {
+	return pack.descriptions[locale]
+}
 
-	if err := scanner.Err(); err != nil {
+func (db *WordDB) loadWordPackImp(langID, packID, path, part string) (*WordPack, bool) {
+	wpf, err := readWordPackFile(path)
+	if err != nil {
 		db.log.Error(err)
 		return nil, false
 	}
 
-	if len(pack.words) == 0 {
+	if len(wpf.Words) == 0 {
 		db.log.Errorw("word pack is empty",
 			"path", path)
 		return nil, false
 	}
 
+	pack := &WordPack{
+		langID:       langID,
+		packID:       packID,
+		part:         part,
+		names:        wpf.Name,
+		descriptions: wpf.Description,
+		words:        wpf.Words,
+	}
+
 	return pack, true
 }
 
 type WordDB struct {
+	mu        sync.RWMutex
 	langIDs   []string
 	packIDs   map[string][]string
 	langNames map[string]string
@@ -94,11 +128,60 @@ func NewWordDB() *WordDB {
 	}
 }
 
+// LoadWordDB loads all word packs from the config. It fails only if none of them can be loaded.
+func LoadWordDB(langs []LanguageConfig) (*WordDB, bool) {
+	wdb := NewWordDB()
+	for _, lang := range langs {
+		for _, pack := range lang.WordPacks {
+			ok := wdb.LoadWordPack(pack.Path, lang.ID, pack.ID, pack.Part, lang.Name, pack.Name)
+			if !ok {
+				wdb.log.Warnw("Error loading word pack",
+					"lang_id", lang.ID,
+					"pack_id", pack.ID)
+				continue
+			}
+
+			if pack.Exclude != "" {
+				wdb.ExcludeWords(pack.Exclude, lang.ID, pack.ID)
+			}
+		}
+	}
+
+	if len(wdb.GetLanguageIDs()) == 0 {
+		wdb.log.Error("no word packs loaded")
+		return nil, false
+	}
+
+	return wdb, true
+}
+
+// Replace atomically substitutes all word packs with the packs from other.
+// Word packs already taken from db remain valid.
+func (db *WordDB) Replace(other *WordDB) {
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.langIDs = other.langIDs
+	db.packIDs = other.packIDs
+	db.langNames = other.langNames
+	db.packNames = other.packNames
+	db.packs = other.packs
+}
+
 func (db *WordDB) GetLanguageIDs() []string {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
 	return db.langIDs
 }
 
 func (db *WordDB) GetWordPackIDs(langID string) ([]string, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
 	names, ok := db.packIDs[langID]
 	if !ok {
 		db.log.Errorw("language doesn't exist", "lang_id", langID)
@@ -109,6 +192,9 @@ func (db *WordDB) GetWordPackIDs(langID string) ([]string, bool) {
 }
 
 func (db *WordDB) GetLanguageName(langID string) (string, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
 	name, ok := db.langNames[langID]
 	if !ok {
 		db.log.Errorw("language doesn't exist", "lang_id", langID)
@@ -119,6 +205,9 @@ func (db *WordDB) GetLanguageName(langID string) (string, bool) {
 }
 
 func (db *WordDB) GetWordPackName(langID, packID string) (string, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
 	var name string
 	names, ok := db.packNames[langID]
 	if ok {
@@ -134,7 +223,26 @@ func (db *WordDB) GetWordPackName(langID, packID string) (string, bool) {
 	return name, true
 }
 
+// GetLocalWordPackName returns the pack name from the pack file in the locale,
+// or the configured name if there is none.
+func (db *WordDB) GetLocalWordPackName(langID, packID, locale string) (string, bool) {
+	pack, ok := db.GetWordPack(langID, packID)
+	if !ok {
+		return "", false
+	}
+
+	name, ok := pack.GetName(locale)
+	if ok {
+		return name, true
+	}
+
+	return db.GetWordPackName(langID, packID)
+}
+
 func (db *WordDB) GetWordPack(langID, packID string) (*WordPack, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
 	pack, ok := db.packs[langID][packID]
 	if !ok {
 		db.log.Errorw("word pack doesn't exist",
@@ -146,12 +254,55 @@ func (db *WordDB) GetWordPack(langID, packID string) (*WordPack, bool) {
 	return pack, true
 }
 
+// ExcludeWords removes words listed in the file from the loaded word pack.
+func (db *WordDB) ExcludeWords(path, langID, packID string) bool {
+	excluded, err := ReadWords(path)
+	if err != nil {
+		db.log.Error(err)
+		return false
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	pack, ok := db.packs[langID][packID]
+	if !ok {
+		return false
+	}
+
+	skip := make(map[string]bool, len(excluded))
+	for _, word := range excluded {
+		skip[word] = true
+	}
+
+	words := make([]Word, 0, len(pack.words))
+	for _, word := range pack.words {
+		if !skip[word.Text] {
+			words = append(words, word)
+		}
+	}
+
+	if len(words) == 0 {
+		db.log.Errorw("all words are excluded from word pack",
+			"lang_id", langID,
+			"pack_id", packID)
+		return false
+	}
+
+	pack.words = words
+
+	return true
+}
+
 func (db *WordDB) LoadWordPack(path, langID, packID, part, langName, packName string) bool {
 	pack, ok := db.loadWordPackImp(langID, packID, path, part)
 	if !ok {
 		return false
 	}
 
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
 	if _, ok := db.packs[langID]; !ok {
 		db.langIDs = append(db.langIDs, langID)
 		db.packIDs[langID] = make([]string, 0)
@@ -2,6 +2,7 @@ package croc
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -83,3 +84,49 @@ func TestWordDB_GetWordPack(t *testing.T) {
 	require.Equal(t, defaultWordPackCfg.part, pack.GetPart())
 	require.NotEmpty(t, pack.GetWord())
 }
+
+func TestWordDB_Replace(t *testing.T) {
+	db := setupTestWordDB(t)
+	oldPack, ok := db.GetWordPack(defaultWordPackCfg.langID, defaultWordPackCfg.packID)
+	require.True(t, ok)
+
+	path := filepath.Join(t.TempDir(), "pack2.txt")
+	err := os.WriteFile(path, []byte("word3\n"), 0644)
+	require.NoError(t, err)
+
+	other := NewWordDB()
+	ok = other.LoadWordPack(path, "fr", "pack2", "", "fr", "pack2")
+	require.True(t, ok)
+
+	db.Replace(other)
+	require.Equal(t, []string{"fr"}, db.GetLanguageIDs())
+
+	_, ok = db.GetWordPack(defaultWordPackCfg.langID, defaultWordPackCfg.packID)
+	require.False(t, ok)
+
+	pack, ok := db.GetWordPack("fr", "pack2")
+	require.True(t, ok)
+	require.Equal(t, "word3", pack.GetWord().Text)
+
+	require.NotEmpty(t, oldPack.GetWord())
+}
+
+func TestWordDB_ExcludeWords(t *testing.T) {
+	db := setupTestWordDB(t)
+
+	path := filepath.Join(t.TempDir(), "exclude.txt")
+	err := os.WriteFile(path, []byte("word1\n"), 0644)
+	require.NoError(t, err)
+
+	ok := db.ExcludeWords(path, defaultWordPackCfg.langID, defaultWordPackCfg.packID)
+	require.True(t, ok)
+
+	pack, ok := db.GetWordPack(defaultWordPackCfg.langID, defaultWordPackCfg.packID)
+	require.True(t, ok)
+	require.Equal(t, "word2", pack.GetWord().Text)
+
+	err = os.WriteFile(path, []byte("word2\n"), 0644)
+	require.NoError(t, err)
+	ok = db.ExcludeWords(path, defaultWordPackCfg.langID, defaultWordPackCfg.packID)
+	require.False(t, ok)
+}
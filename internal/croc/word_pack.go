@@ -0,0 +1,178 @@
+package croc
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type Word struct {
+	Text       string   `toml:"word" json:"word"`
+	Difficulty int      `toml:"difficulty" json:"difficulty"`
+	Tags       []string `toml:"tags" json:"tags"`
+	Taboo      []string `toml:"taboo" json:"taboo"`
+	Definition string   `toml:"definition" json:"definition"`
+	Answers    []string `toml:"answers" json:"answers"`
+}
+
+// wordPackFile is the content of a word pack file.
+// Plain text and CSV files contain only words.
+type wordPackFile struct {
+	Name        map[string]string `toml:"name" json:"name"`
+	Description map[string]string `toml:"description" json:"description"`
+	Words       []Word            `toml:"words" json:"words"`
+}
+
+const csvListSep = ";"
+
+func readWordPackFile(path string) (*wordPackFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var wpf *wordPackFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		wpf = &wordPackFile{}
+		_, err = toml.NewDecoder(file).Decode(wpf)
+	case ".json":
+		wpf = &wordPackFile{}
+		err = json.NewDecoder(file).Decode(wpf)
+	case ".csv":
+		wpf, err = readWordPackCSV(file)
+	default:
+		wpf, err = readWordPackText(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	words := wpf.Words[:0]
+	for _, word := range wpf.Words {
+		word.Text = strings.TrimSpace(word.Text)
+		if word.Text == "" {
+			continue
+		}
+
+		if word.Difficulty < 0 || word.Difficulty > maxWordDifficulty {
+			return nil, fmt.Errorf("%s: word %q: difficulty must be from 1 to %d", path, word.Text, maxWordDifficulty)
+		}
+		words = append(words, word)
+	}
+	wpf.Words = words
+
+	return wpf, nil
+}
+
+func readWordPackText(r io.Reader) (*wordPackFile, error) {
+	wpf := &wordPackFile{Words: make([]Word, 0, 200)}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		wpf.Words = append(wpf.Words, Word{Text: scanner.Text()})
+	}
+
+	return wpf, scanner.Err()
+}
+
+// readWordPackCSV reads a CSV file with a header. Only the word column is required,
+// list columns are separated by semicolons.
+func readWordPackCSV(r io.Reader) (*wordPackFile, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "word", "difficulty", "tags", "taboo", "definition", "answers":
+			columns[name] = i
+		default:
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+	}
+
+	if _, ok := columns["word"]; !ok {
+		return nil, errors.New("column \"word\" is required")
+	}
+
+	wpf := &wordPackFile{Words: make([]Word, 0, 200)}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		list := func(name string) []string {
+			value := field(name)
+			if value == "" {
+				return nil
+			}
+
+			items := strings.Split(value, csvListSep)
+			for i := range items {
+				items[i] = strings.TrimSpace(items[i])
+			}
+			return items
+		}
+
+		word := Word{
+			Text:       field("word"),
+			Tags:       list("tags"),
+			Taboo:      list("taboo"),
+			Definition: field("definition"),
+			Answers:    list("answers"),
+		}
+
+		if difficulty := field("difficulty"); difficulty != "" {
+			word.Difficulty, err = strconv.Atoi(difficulty)
+			if err != nil {
+				return nil, fmt.Errorf("word %q: %w", word.Text, err)
+			}
+		}
+
+		wpf.Words = append(wpf.Words, word)
+	}
+
+	return wpf, nil
+}
+
+// ReadWords returns the words of the word pack file in any supported format.
+func ReadWords(path string) ([]string, error) {
+	wpf, err := readWordPackFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	words := make([]string, 0, len(wpf.Words))
+	for _, word := range wpf.Words {
+		words = append(words, word.Text)
+	}
+
+	return words, nil
+}
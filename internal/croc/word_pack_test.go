@@ -0,0 +1,114 @@
+package croc
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestWordPack(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(content), 0644)
+	require.NoError(t, err)
+
+	return path
+}
+
+func TestReadWordPackFile(t *testing.T) {
+	expected := []Word{
+		{
+			Text:       "cat",
+			Difficulty: 1,
+			Tags:       []string{"pets"},
+			Taboo:      []string{"dog", "meow"},
+			Definition: "a small furry animal",
+			Answers:    []string{"kitty"},
+		},
+		{Text: "dog"},
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		meta    bool
+	}{
+		{
+			name: "pack.toml",
+			content: `
+[name]
+en = "Animals"
+[description]
+en = "Pets and wild animals"
+
+[[words]]
+word = "cat"
+difficulty = 1
+tags = ["pets"]
+taboo = ["dog", "meow"]
+definition = "a small furry animal"
+answers = ["kitty"]
+
+[[words]]
+word = "dog"
+`,
+			meta: true,
+		},
+		{
+			name: "pack.json",
+			content: `{
+"name": {"en": "Animals"},
+"description": {"en": "Pets and wild animals"},
+"words": [
+	{"word": "cat", "difficulty": 1, "tags": ["pets"], "taboo": ["dog", "meow"],
+	 "definition": "a small furry animal", "answers": ["kitty"]},
+	{"word": "dog"}
+]}`,
+			meta: true,
+		},
+		{
+			name: "pack.csv",
+			content: "word,difficulty,tags,taboo,definition,answers\n" +
+				"cat,1,pets,dog; meow,a small furry animal,kitty\n" +
+				"dog,,,,,\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestWordPack(t, tt.name, tt.content)
+			wpf, err := readWordPackFile(path)
+			require.NoError(t, err)
+			require.Equal(t, expected, wpf.Words)
+			if tt.meta {
+				require.Equal(t, "Animals", wpf.Name["en"])
+				require.Equal(t, "Pets and wild animals", wpf.Description["en"])
+			}
+		})
+	}
+}
+
+func TestReadWordPackFileErrors(t *testing.T) {
+	path := writeTestWordPack(t, "pack.csv", "word,color\ncat,black\n")
+	_, err := readWordPackFile(path)
+	require.Error(t, err)
+
+	path = writeTestWordPack(t, "pack.csv", "definition\nanimal\n")
+	_, err = readWordPackFile(path)
+	require.Error(t, err)
+
+	path = writeTestWordPack(t, "pack.csv", "word,difficulty\ncat,6\n")
+	_, err = readWordPackFile(path)
+	require.Error(t, err)
+
+	path = writeTestWordPack(t, "pack.toml", "[[words]\n")
+	_, err = readWordPackFile(path)
+	require.Error(t, err)
+}
+
+func TestReadWords(t *testing.T) {
+	path := writeTestWordPack(t, "pack.txt", "cat\n\n dog \n")
+	words, err := ReadWords(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"cat", "dog"}, words)
+}
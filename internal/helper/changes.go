@@ -0,0 +1,297 @@
+package helper
+
+import (
+	"crocodiler/internal/croc"
+	"encoding/json"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	changeAdded   = "added"
+	changeChanged = "changed"
+	changeRemoved = "removed"
+)
+
+// dictChange is the record of the changelog written by UpdateDictionary.
+type dictChange struct {
+	Time   time.Time `json:"time"`
+	Lang   string    `json:"lang"`
+	Pack   string    `json:"pack,omitempty"`
+	Part   string    `json:"part,omitempty"`
+	Target string    `json:"target,omitempty"`
+	Word   string    `json:"word"`
+	Action string    `json:"action"`
+}
+
+// addChange records the change of the definition, or of the translation if the target language is set.
+func (lu *langUpdater) addChange(packID, part, target, word, action string) {
+	lu.changes = append(lu.changes, dictChange{
+		Time:   time.Now().UTC().Truncate(time.Second),
+		Lang:   lu.langID,
+		Pack:   packID,
+		Part:   part,
+		Target: target,
+		Word:   word,
+		Action: action,
+	})
+}
+
+// printChanges prints the number of changes of the pack, and the changed words in the dry run.
+func (lu *langUpdater) printChanges(packID string) {
+	counts := make(map[string]int)
+	var removedTr int
+	for _, change := range lu.changes {
+		if change.Pack != packID {
+			continue
+		}
+
+		if change.Target != "" {
+			removedTr++
+			continue
+		}
+
+		counts[change.Action]++
+		if lu.opts.DryRun {
+			fmt.Printf("\t%s %s\n", change.Action, change.Word)
+		}
+	}
+
+	fmt.Printf("Added: %d. Changed: %d. Removed: %d, translations: %d.\n",
+		counts[changeAdded], counts[changeChanged], counts[changeRemoved], removedTr)
+}
+
+// bucket returns the nested bucket, empty names are skipped. Missing buckets are created
+// in writable transactions, in read-only transactions nil is returned for them.
+func bucket(tx *bolt.Tx, names ...string) (*bolt.Bucket, error) {
+	var bkt *bolt.Bucket
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+
+		if tx.Writable() {
+			var err error
+			if bkt == nil {
+				bkt, err = tx.CreateBucketIfNotExists([]byte(name))
+			} else {
+				bkt, err = bkt.CreateBucketIfNotExists([]byte(name))
+			}
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if bkt == nil {
+			bkt = tx.Bucket([]byte(name))
+		} else {
+			bkt = bkt.Bucket([]byte(name))
+		}
+		if bkt == nil {
+			return nil, nil
+		}
+	}
+
+	return bkt, nil
+}
+
+// getValue returns the value of the key, or nil if the bucket is missing.
+func getValue(bkt *bolt.Bucket, key string) []byte {
+	if bkt == nil {
+		return nil
+	}
+
+	return bkt.Get([]byte(key))
+}
+
+// put stores the value unless the bucket is missing or read-only.
+func put(bkt *bolt.Bucket, key string, value []byte) error {
+	if bkt == nil || !bkt.Writable() {
+		return nil
+	}
+
+	return bkt.Put([]byte(key), value)
+}
+
+// del deletes the key unless the bucket is missing or read-only.
+func del(bkt *bolt.Bucket, key string) error {
+	if bkt == nil || !bkt.Writable() {
+		return nil
+	}
+
+	return bkt.Delete([]byte(key))
+}
+
+// removeDefinition deletes the definition of the pack word and its translations,
+// trBkt is the bucket of target languages of the translations.
+func (lu *langUpdater) removeDefinition(bkt, trBkt *bolt.Bucket, pack WordPackConfig, word string) error {
+	err := del(bkt, word)
+	if err != nil {
+		return err
+	}
+	lu.addChange(pack.ID, pack.Part, "", word, changeRemoved)
+
+	if trBkt == nil {
+		return nil
+	}
+
+	var targets []string
+	_ = trBkt.ForEach(func(name, v []byte) error {
+		if v == nil && trBkt.Bucket(name).Get([]byte(word)) != nil {
+			targets = append(targets, string(name))
+		}
+		return nil
+	})
+
+	for _, target := range targets {
+		err = del(trBkt.Bucket([]byte(target)), word)
+		if err != nil {
+			return err
+		}
+		lu.addChange(pack.ID, pack.Part, target, word, changeRemoved)
+	}
+
+	return nil
+}
+
+// commit commits the transaction unless it is the dry run.
+func (lu *langUpdater) commit(tx *bolt.Tx) error {
+	if lu.opts.DryRun {
+		return tx.Rollback()
+	}
+
+	return tx.Commit()
+}
+
+type staleKey struct {
+	bkt    *bolt.Bucket
+	part   string
+	target string
+	word   string
+}
+
+// findStale returns keys of the bucket, and of its nested buckets, which are not valid.
+// Keys of the nested buckets are passed to valid with the names of the buckets as parts.
+func findStale(bkt *bolt.Bucket, part string, valid func(part, word string) bool) []staleKey {
+	var stale []staleKey
+	_ = bkt.ForEach(func(k, v []byte) error {
+		if v == nil {
+			stale = append(stale, findStale(bkt.Bucket(k), string(k), valid)...)
+			return nil
+		}
+
+		if !valid(part, string(k)) {
+			stale = append(stale, staleKey{bkt: bkt, part: part, word: string(k)})
+		}
+		return nil
+	})
+
+	return stale
+}
+
+// pruneStale looks for definitions and translations of words which are neither in the word packs
+// nor loaded for the lookup index, and deletes them if pruning is on.
+func (lu *langUpdater) pruneStale() error {
+	indexed := make(map[wordKey]bool, len(lu.entries))
+	for _, wk := range lu.entries {
+		indexed[wk] = true
+	}
+
+	tx, err := lu.db.Begin(lu.opts.Prune && !lu.opts.DryRun)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var stale []staleKey
+	bkt := tx.Bucket([]byte(lu.langID))
+	if bkt != nil {
+		stale = findStale(bkt, "", func(part, word string) bool {
+			wk := wordKey{part: part, word: word}
+			return lu.packWords[wk] || indexed[wk]
+		})
+	}
+
+	// translations are stored as <part>/<target>/<word>, or as <target>/<word> without parts of speech
+	var staleTr []staleKey
+	trBkt := tx.Bucket([]byte(croc.TranslationsBucket))
+	if trBkt != nil {
+		trBkt = trBkt.Bucket([]byte(lu.langID))
+	}
+	if trBkt != nil {
+		_ = trBkt.ForEach(func(name, _ []byte) error {
+			found := findStale(trBkt.Bucket(name), "", func(target, word string) bool {
+				return lu.packWords[wordKey{part: string(name), word: word}] ||
+					(target == "" && lu.packWords[wordKey{word: word}])
+			})
+			for _, sk := range found {
+				if lu.parts {
+					sk.part, sk.target = string(name), sk.part
+				} else {
+					sk.target = string(name)
+				}
+				staleTr = append(staleTr, sk)
+			}
+			return nil
+		})
+	}
+
+	if !lu.opts.Prune {
+		if len(stale) > 0 || len(staleTr) > 0 {
+			fmt.Printf("Stale definitions of %s: %d, translations: %d. Run with --prune to delete them.\n",
+				lu.langID, len(stale), len(staleTr))
+		}
+		return nil
+	}
+
+	for _, sk := range append(stale, staleTr...) {
+		err = del(sk.bkt, sk.word)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, sk := range stale {
+		lu.addChange("", sk.part, "", sk.word, changeRemoved)
+		if lu.opts.DryRun {
+			fmt.Printf("\t%s %s\n", changeRemoved, sk.word)
+		}
+	}
+	for _, sk := range staleTr {
+		lu.addChange("", sk.part, sk.target, sk.word, changeRemoved)
+	}
+
+	fmt.Printf("Removed stale definitions of %s: %d, translations: %d.\n", lu.langID, len(stale), len(staleTr))
+
+	return lu.commit(tx)
+}
+
+// writeChangelog appends the changes to the changelog as JSON Lines.
+func writeChangelog(path string, changes []dictChange) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func(file *os.File) {
+		err := file.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(file)
+
+	enc := json.NewEncoder(file)
+	for _, change := range changes {
+		err = enc.Encode(change)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Written %d changes to %s.\n", len(changes), path)
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package helper
+
+import (
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindStale(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "dict.db"), 0600, nil)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		keys := [][]string{{"en", "cat"}, {"en", "old"}, {"en", "noun", "dog"}, {"en", "noun", "bone"}, {"en", "verb", "run"}}
+		for _, names := range keys {
+			bkt, err := bucket(tx, names[:len(names)-1]...)
+			if err != nil {
+				return err
+			}
+			err = put(bkt, names[len(names)-1], []byte("{}"))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		valid map[wordKey]bool
+		stale []wordKey
+	}{
+		{
+			name: "all valid",
+			valid: map[wordKey]bool{
+				{word: "cat"}: true, {word: "old"}: true,
+				{"noun", "dog"}: true, {"noun", "bone"}: true, {"verb", "run"}: true,
+			},
+		},
+		{
+			name:  "stale words and parts",
+			valid: map[wordKey]bool{{word: "cat"}: true, {"noun", "dog"}: true},
+			stale: []wordKey{{word: "old"}, {"noun", "bone"}, {"verb", "run"}},
+		},
+		{
+			name: "the part of speech matters",
+			valid: map[wordKey]bool{
+				{word: "cat"}: true, {word: "old"}: true,
+				{"verb", "dog"}: true, {"noun", "bone"}: true, {"noun", "run"}: true,
+			},
+			stale: []wordKey{{"noun", "dog"}, {"verb", "run"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := db.View(func(tx *bolt.Tx) error {
+				found := findStale(tx.Bucket([]byte("en")), "", func(part, word string) bool {
+					return tt.valid[wordKey{part: part, word: word}]
+				})
+
+				var stale []wordKey
+				for _, sk := range found {
+					stale = append(stale, wordKey{part: sk.part, word: sk.word})
+				}
+				require.ElementsMatch(t, tt.stale, stale)
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestBucketReadOnly(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "dict.db"), 0600, nil)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	err = db.View(func(tx *bolt.Tx) error {
+		bkt, err := bucket(tx, "en", "noun")
+		require.Nil(t, bkt)
+		require.Nil(t, getValue(bkt, "cat"))
+		require.NoError(t, put(bkt, "cat", []byte("{}")))
+		require.NoError(t, del(bkt, "cat"))
+		return err
+	})
+	require.NoError(t, err)
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		bkt, err := bucket(tx, "en", "")
+		require.NotNil(t, bkt)
+		require.NoError(t, put(bkt, "cat", []byte("{}")))
+		return err
+	})
+	require.NoError(t, err)
+
+	err = db.View(func(tx *bolt.Tx) error {
+		bkt, err := bucket(tx, "en", "")
+		require.Equal(t, []byte("{}"), getValue(bkt, "cat"))
+		// the read-only bucket is not changed
+		require.NoError(t, del(bkt, "cat"))
+		require.NotNil(t, getValue(bkt, "cat"))
+		return err
+	})
+	require.NoError(t, err)
+}
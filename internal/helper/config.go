@@ -1,25 +1,71 @@
 package helper
 
 import (
-	"github.com/knadh/koanf/parsers/toml"
-	"github.com/knadh/koanf/providers/file"
-	"github.com/knadh/koanf/v2"
+	"crocodiler/internal/config"
+	"sort"
 )
 
+const DefaultConfigPath = "helper.toml"
+
 type Config struct {
-	DictPath  string `koanf:"dict_path"`
-	Languages []LanguageConfig
+	DictPath string `koanf:"dict_path"`
+	// ChangelogPath is the JSON Lines file changes of definitions are appended to,
+	// next to the dictionary by default.
+	ChangelogPath string `koanf:"changelog_path"`
+	Languages     []LanguageConfig
+}
+
+func (cfg Config) getChangelogPath() string {
+	if cfg.ChangelogPath == "" {
+		return cfg.DictPath + ".changelog.jsonl"
+	}
+	return cfg.ChangelogPath
 }
 
 type LanguageConfig struct {
-	ID        string
+	ID string
+	// Dict is the single dictionary of the language, kept for older configs.
 	Dict      DictConfig
+	Dicts     []DictConfig
 	WordPacks []WordPackConfig `koanf:"word_packs"`
+	// GenPacks are word packs generated from the dictionaries by --gen-packs.
+	GenPacks []GenPackConfig `koanf:"gen_packs"`
 }
 
 type DictConfig struct {
-	Path  string
-	Parts bool
+	// Path is a dump file, a glob pattern of dump shards or "-" for the standard input.
+	// Gzip and bzip2 compressed dumps are detected automatically.
+	// StarDict dictionaries are set by paths of their .ifo files.
+	Path string
+	// Format is one of wiktextract (default), tsv, stardict or dsl.
+	Format string
+	// Priority decides which dictionary defines the word if several of them have it.
+	Priority int
+	Parts    bool
+	// Translations lists language codes to keep translations into, all languages if empty.
+	Translations []string
+}
+
+func (dict DictConfig) getFormat() string {
+	if dict.Format == "" {
+		return formatWiktextract
+	}
+	return dict.Format
+}
+
+// getDicts returns dictionaries of the language ordered by priority.
+func (lang LanguageConfig) getDicts() []DictConfig {
+	dicts := make([]DictConfig, 0, len(lang.Dicts)+1)
+	if lang.Dict.Path != "" {
+		dicts = append(dicts, lang.Dict)
+	}
+	dicts = append(dicts, lang.Dicts...)
+
+	sort.SliceStable(dicts, func(i, j int) bool {
+		return dicts[i].Priority > dicts[j].Priority
+	})
+
+	return dicts
 }
 
 type WordPackConfig struct {
@@ -28,12 +74,31 @@ type WordPackConfig struct {
 	Part string
 }
 
-func LoadConfig() (Config, error) {
-	var kConf = koanf.New("/")
+// GenPackConfig sets filters of the word pack generated from dictionary entries.
+type GenPackConfig struct {
+	// Path is the pack file to write, the coverage report is written next to it with the .report extension.
+	Path string
+	// Parts lists parts of speech of the words, any part if empty.
+	Parts  []string
+	MinLen int `koanf:"min_len"`
+	MaxLen int `koanf:"max_len"`
+	// MinSenses is the least number of senses in definitions of the word.
+	MinSenses int `koanf:"min_senses"`
+	// FreqPath is a frequency list with one word per line, the most frequent first, optionally followed by its count.
+	// Only words of the list are taken if it is set.
+	FreqPath string `koanf:"freq_path"`
+	// MaxRank keeps only words among the MaxRank most frequent ones.
+	MaxRank int `koanf:"max_rank"`
+	// MaxWords limits the pack size keeping the most frequent words.
+	MaxWords int `koanf:"max_words"`
+	// ExcludePath is a word pack file with words to leave out.
+	ExcludePath string `koanf:"exclude_path"`
+}
 
+func LoadConfig(path string) (Config, error) {
 	var cfg Config
 
-	err := kConf.Load(file.Provider("helper.toml"), toml.Parser())
+	kConf, err := config.Load(path, config.HelperEnvPrefix, nil)
 	if err != nil {
 		return cfg, err
 	}
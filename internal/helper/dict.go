@@ -1,68 +1,241 @@
 package helper
 
 import (
-	"bufio"
+	"bytes"
+	"crocodiler/internal/croc"
 	"encoding/json"
+	"errors"
 	"fmt"
 	bolt "go.etcd.io/bbolt"
+	"io"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 )
 
+type translation struct {
+	Code string
+	Word string
+}
+
+type synonym struct {
+	Word string
+}
+
+type example struct {
+	Text string
+}
+
+// formLink refers to the lemma of the inflected or alternative form.
+type formLink struct {
+	Word string
+}
+
 type wordDef struct {
-	Word     string
-	Pos      string
-	LangCode string `json:"lang_code"`
-	Title    string
-	Redirect string
-	Senses   []struct {
-		Glosses    []string
-		RawGlosses []string `json:"raw_glosses"`
-	}
+	Word          string
+	Pos           string
+	LangCode      string `json:"lang_code"`
+	Title         string
+	Redirect      string
+	Translations  []translation
+	Synonyms      []synonym
+	EtymologyText string `json:"etymology_text"`
+	Senses        []wordSense
 }
 
-func UpdateDictionary() {
-	cfg, err := LoadConfig()
+type wordSense struct {
+	Glosses      []string
+	RawGlosses   []string `json:"raw_glosses"`
+	Translations []translation
+	Examples     []example
+	Synonyms     []synonym
+	FormOf       []formLink `json:"form_of"`
+	AltOf        []formLink `json:"alt_of"`
+}
+
+const (
+	// maxTranslations limits the number of stored translations of a word into one language.
+	maxTranslations = 5
+	maxExamples     = 2
+	maxSynonyms     = 5
+	maxEtymologyLen = 300
+)
+
+// UpdateOptions change how UpdateDictionary writes the dictionary.
+type UpdateOptions struct {
+	// DryRun prints changes of definitions without writing them.
+	DryRun bool
+	// Prune deletes definitions of words which are not in any configured word pack,
+	// and definitions of pack words which are no longer found in the dictionaries.
+	Prune bool
+}
+
+func UpdateDictionary(cfgPath string, opts UpdateOptions) {
+	cfg, err := LoadConfig(cfgPath)
 	if err != nil {
 		panic(err)
 	}
 
-	db, err := bolt.Open(cfg.DictPath, 0600, nil)
+	if opts.DryRun {
+		fmt.Println("Dry run, the dictionary is not changed.")
+		previewDictionary(cfg, opts)
+		return
+	}
+
+	// the copy of the dictionary is updated and replaces it at the end,
+	// so the running bot keeps reading the old one until it notices the new file
+	tmpPath := cfg.DictPath + ".tmp"
+	err = copyFile(cfg.DictPath, tmpPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		panic(err)
+	}
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	db, err := bolt.Open(tmpPath, 0600, nil)
 	if err != nil {
 		panic(err)
 	}
-	defer func(db *bolt.DB) {
-		err := db.Close()
+
+	changes := updateLanguages(db, cfg.Languages, opts)
+
+	err = db.Close()
+	if err != nil {
+		panic(err)
+	}
+
+	err = os.Rename(tmpPath, cfg.DictPath)
+	if err != nil {
+		panic(err)
+	}
+
+	if len(changes) == 0 {
+		return
+	}
+
+	err = writeChangelog(cfg.getChangelogPath(), changes)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// previewDictionary prints the changes of definitions reading the dictionary without copying it.
+// A missing dictionary is previewed as an empty one.
+func previewDictionary(cfg Config, opts UpdateOptions) {
+	path := cfg.DictPath
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		dir, err := os.MkdirTemp("", "crocodiler")
 		if err != nil {
 			panic(err)
 		}
-	}(db)
+		defer func() { _ = os.RemoveAll(dir) }()
+
+		path = filepath.Join(dir, "dict.db")
+		db, err := bolt.Open(path, 0600, nil)
+		if err != nil {
+			panic(err)
+		}
+		_ = db.Close()
+	}
+
+	db, err := bolt.Open(path, 0400, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		panic(err)
+	}
+	defer func() { _ = db.Close() }()
 
-	for _, lang := range cfg.Languages {
+	updateLanguages(db, cfg.Languages, opts)
+}
+
+// copyFile copies the file to the path, replacing the existing file.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// updateLanguages updates definitions of the languages and returns their changes.
+func updateLanguages(db *bolt.DB, langs []LanguageConfig, opts UpdateOptions) []dictChange {
+	var changes []dictChange
+	for _, lang := range langs {
 		lu, err := newLangUpdater(db, lang)
 		if err != nil {
 			log.Println(err)
 			continue
 		}
+		lu.opts = opts
 
+		packsUpdated := true
 		for _, pack := range lang.WordPacks {
 			err := lu.updateWordPack(pack)
+			if err != nil {
+				log.Println(err)
+				packsUpdated = false
+			}
+		}
+
+		err = lu.updateIndex()
+		if err != nil {
+			log.Println(err)
+			packsUpdated = false
+		}
+
+		fmt.Println(lu.sanitized.String())
+
+		// words of packs failed to update would be taken for stale ones
+		if packsUpdated {
+			err = lu.pruneStale()
 			if err != nil {
 				log.Println(err)
 			}
+		} else {
+			fmt.Printf("Skipped looking for stale definitions of %s, some word packs are not updated.\n", lang.ID)
 		}
+
+		changes = append(changes, lu.changes...)
 	}
+
+	return changes
 }
 
 type glossary struct {
-	senses   []string
-	redirect string
+	priority int
+	senses   []croc.DictSense
+	// lemmas are words the form-of and alt-of senses refer to, their senses replace formSenses
+	lemmas       []string
+	formSenses   []croc.DictSense
+	synonyms     []string
+	etymology    string
+	redirect     string
+	translations map[string][]string
 }
 
-func newGlossary(wd *wordDef) *glossary {
-	gloss := &glossary{senses: make([]string, 0, len(wd.Senses))}
-	gloss.addSenses(wd)
+func newGlossary(wd *wordDef, priority int, targets map[string]bool) *glossary {
+	gloss := &glossary{
+		priority:     priority,
+		senses:       make([]croc.DictSense, 0, len(wd.Senses)),
+		redirect:     wd.Redirect,
+		translations: make(map[string][]string),
+	}
+	gloss.addSenses(wd, targets)
 	return gloss
 }
 
@@ -72,10 +245,16 @@ func (gloss *glossary) lastSense() string {
 		return ""
 	}
 
-	return gloss.senses[size-1]
+	return gloss.senses[size-1].Gloss
 }
 
-func (gloss *glossary) addSenses(wd *wordDef) {
+func (gloss *glossary) addSenses(wd *wordDef, targets map[string]bool) {
+	gloss.addEntryTranslations(wd, targets)
+	gloss.synonyms = addSynonyms(gloss.synonyms, wd.Synonyms)
+	if gloss.etymology == "" {
+		gloss.etymology = shortEtymology(wd.EtymologyText)
+	}
+
 	for i := range wd.Senses {
 		var sense string
 		if len(wd.Senses[i].RawGlosses) > 0 {
@@ -88,140 +267,360 @@ func (gloss *glossary) addSenses(wd *wordDef) {
 			continue
 		}
 
-		if sense != gloss.lastSense() {
-			gloss.senses = append(gloss.senses, sense)
+		if sense == gloss.lastSense() {
+			continue
+		}
+
+		ds := croc.DictSense{Gloss: sense}
+		for _, ex := range wd.Senses[i].Examples {
+			if len(ds.Examples) == maxExamples {
+				break
+			}
+			if text := strings.TrimSpace(ex.Text); text != "" {
+				ds.Examples = append(ds.Examples, text)
+			}
 		}
+		ds.Synonyms = addSynonyms(nil, wd.Senses[i].Synonyms)
+
+		links := slices.Concat(wd.Senses[i].FormOf, wd.Senses[i].AltOf)
+		if len(links) == 0 {
+			gloss.senses = append(gloss.senses, ds)
+			continue
+		}
+
+		gloss.formSenses = append(gloss.formSenses, ds)
+		for _, link := range links {
+			if link.Word != "" && !strings.EqualFold(link.Word, wd.Word) && !slices.Contains(gloss.lemmas, link.Word) {
+				gloss.lemmas = append(gloss.lemmas, link.Word)
+			}
+		}
+	}
+}
+
+func addSynonyms(words []string, synonyms []synonym) []string {
+	for _, syn := range synonyms {
+		if len(words) == maxSynonyms {
+			break
+		}
+		if syn.Word != "" && !slices.Contains(words, syn.Word) {
+			words = append(words, syn.Word)
+		}
+	}
+
+	return words
+}
+
+// shortEtymology returns the first paragraph of the etymology cut to maxEtymologyLen runes.
+func shortEtymology(text string) string {
+	text, _, _ = strings.Cut(strings.TrimSpace(text), "\n")
+
+	runes := []rune(text)
+	if len(runes) <= maxEtymologyLen {
+		return text
+	}
+
+	runes = runes[:maxEtymologyLen]
+	if i := strings.LastIndexAny(string(runes), ".;"); i > 0 {
+		return string(runes)[:i+1]
+	}
+
+	return string(runes) + "…"
+}
+
+func (gloss *glossary) addEntryTranslations(wd *wordDef, targets map[string]bool) {
+	gloss.addTranslations(wd.Translations, targets)
+	for i := range wd.Senses {
+		gloss.addTranslations(wd.Senses[i].Translations, targets)
 	}
 }
 
-func (gloss *glossary) getDefinition() string {
-	if len(gloss.senses) == 1 {
-		return gloss.senses[0]
+// addTranslations keeps translations into the target languages, or into all languages if targets are empty.
+func (gloss *glossary) addTranslations(trs []translation, targets map[string]bool) {
+	for _, tr := range trs {
+		if tr.Code == "" || tr.Word == "" {
+			continue
+		}
+
+		if len(targets) > 0 && !targets[tr.Code] {
+			continue
+		}
+
+		words := gloss.translations[tr.Code]
+		if len(words) == maxTranslations || slices.Contains(words, tr.Word) {
+			continue
+		}
+
+		gloss.translations[tr.Code] = append(words, tr.Word)
 	}
+}
 
-	var def string
-	for i, sense := range gloss.senses {
-		def += fmt.Sprintf("%d) %s\n", i+1, sense)
+func (gloss *glossary) getEntry() ([]byte, error) {
+	entry := croc.DictEntry{
+		Version:   croc.DictEntryVersion,
+		Senses:    gloss.senses,
+		Synonyms:  gloss.synonyms,
+		Etymology: gloss.etymology,
 	}
 
-	return def
+	return json.Marshal(entry)
+}
+
+// wordKey is the place of the word entry in the dictionary, the part is empty without parts of speech.
+type wordKey struct {
+	part string
+	word string
 }
 
 type langUpdater struct {
 	db      *bolt.DB
 	langID  string
+	parts   bool
+	targets map[string]bool
 	allDefs map[string]*glossary
+	// entries are keys of allDefs with entries of dictionary words as they are spelled in the dictionaries
+	entries map[string]wordKey
+	// redirects map lowercase words to the words they redirect to
+	redirects map[string]string
+	// stored are the words of the word packs written to the dictionary
+	stored []wordKey
+	// packWords are words of all word packs of the language, found in the dictionaries or not
+	packWords map[wordKey]bool
+	sanitized sanitizeStats
+	opts      UpdateOptions
+	changes   []dictChange
+	needed    map[string]bool
+	peakHeap  uint64
 }
 
+type langSource struct {
+	cfg DictConfig
+	src DictSource
+}
+
+// newLangUpdater collects words of the language word packs and loads only their entries from the dictionaries.
+// Redirect targets and lemmas are loaded in the second pass over the dictionaries if they are not among the words.
 func newLangUpdater(db *bolt.DB, lang LanguageConfig) (*langUpdater, error) {
-	fmt.Printf("Loading dictionary from file %s...\n", lang.Dict.Path)
-	file, err := os.Open(lang.Dict.Path)
-	if err != nil {
-		return nil, err
+	lu := &langUpdater{
+		db:        db,
+		langID:    lang.ID,
+		allDefs:   make(map[string]*glossary),
+		entries:   make(map[string]wordKey),
+		redirects: make(map[string]string),
+		packWords: make(map[wordKey]bool),
+		needed:    make(map[string]bool),
+		targets:   make(map[string]bool),
 	}
-	defer func(file *os.File) {
-		err := file.Close()
+
+	dicts := lang.getDicts()
+	sources := make([]langSource, 0, len(dicts))
+	for _, dict := range dicts {
+		src, err := newDictSource(dict, lang.ID)
 		if err != nil {
-			log.Println(err)
+			return nil, err
 		}
-	}(file)
+		sources = append(sources, langSource{cfg: dict, src: src})
 
-	allDefs := make(map[string]*glossary)
+		lu.parts = lu.parts || dict.Parts
+		for _, target := range dict.Translations {
+			lu.targets[target] = true
+		}
+	}
 
-	var wordCount int
-	scanner := bufio.NewScanner(file)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
-	for scanner.Scan() {
-		defJson := scanner.Text()
-		wd := &wordDef{}
-		err = json.Unmarshal([]byte(defJson), wd)
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("language %s has no dictionaries", lang.ID)
+	}
+
+	for _, pack := range lang.WordPacks {
+		words, err := croc.ReadWords(pack.Path)
 		if err != nil {
 			log.Println(err)
 			continue
 		}
 
-		if wd.LangCode != "" && wd.LangCode != lang.ID {
-			continue
+		for _, word := range words {
+			lu.needed[strings.ToLower(word)] = true
 		}
+	}
 
-		if len(wd.Senses) == 0 && wd.Word != "" {
-			continue
-		}
+	if len(lu.needed) == 0 {
+		return nil, fmt.Errorf("word packs of language %s are empty", lang.ID)
+	}
 
-		var key string
-		if wd.Word != "" {
-			if lang.Dict.Parts {
-				key = wd.Pos + "/" + wd.Word
-			} else {
-				key = wd.Word
+	fmt.Printf("Loading %d words...\n", len(lu.needed))
+	wordCount, err := lu.load(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	links := lu.missingLinks()
+	if len(links) > 0 {
+		rereadable := make([]langSource, 0, len(sources))
+		for _, ls := range sources {
+			if ls.cfg.Path == stdinPath {
+				fmt.Printf("Skipped redirect targets and lemmas in %s, the standard input can't be read twice.\n",
+					ls.cfg.Path)
+				continue
 			}
-		} else {
-			key = "redirect/" + wd.Title
+			rereadable = append(rereadable, ls)
 		}
 
-		gloss, exist := allDefs[key]
-		if exist {
-			gloss.addSenses(wd)
-			continue
+		fmt.Printf("Loading %d redirect targets and lemmas...\n", len(links))
+		lu.needed = links
+		cnt, err := lu.load(rereadable)
+		if err != nil {
+			return nil, err
+		}
+		wordCount += cnt
+	}
+	lu.needed = nil
+
+	if wordCount == 0 {
+		return nil, fmt.Errorf("Dictionaries of language %s are empty\n", lang.ID)
+	}
+
+	fmt.Printf("Loaded %d words. Peak memory: ~%d MiB.\n", wordCount, lu.peakHeap/1024/1024)
+
+	return lu, nil
+}
+
+// load reads the sources and keeps entries of the needed words only.
+func (lu *langUpdater) load(sources []langSource) (int, error) {
+	var wordCount int
+	for _, ls := range sources {
+		fmt.Printf("Reading %s dictionary %s...\n", ls.cfg.getFormat(), ls.cfg.Path)
+
+		var st loadStats
+		needed := func(word string) bool {
+			return lu.needed[strings.ToLower(word)]
 		}
+		add := func(wd *wordDef) {
+			if lu.addEntry(wd, ls.cfg.Priority) {
+				st.words++
+			}
 
-		gloss = newGlossary(wd)
+			// targets and lemmas found after the words referring to them are loaded in the same pass
+			if wd.Redirect != "" {
+				lu.needed[strings.ToLower(wd.Redirect)] = true
+			}
+			for i := range wd.Senses {
+				for _, link := range slices.Concat(wd.Senses[i].FormOf, wd.Senses[i].AltOf) {
+					lu.needed[strings.ToLower(link.Word)] = true
+				}
+			}
+		}
 
-		allDefs[key] = gloss
-		allDefs["low/"+strings.ToLower(key)] = gloss
-		if wd.Word != "" && lang.Dict.Parts {
-			key = "any-pos/" + wd.Word
-			allDefs[key] = gloss
-			allDefs["low/"+strings.ToLower(key)] = gloss
+		err := ls.src.Read(needed, add, &st)
+		if err != nil {
+			return wordCount, err
 		}
-		wordCount++
+
+		st.updatePeakHeap()
+		lu.peakHeap = max(lu.peakHeap, st.peakHeap)
+		wordCount += st.words
+
+		fmt.Printf("Lines: %d. Malformed: %d. Other languages: %d. Words: %d.\n",
+			st.lines, st.malformed, st.otherLang, st.words)
 	}
 
-	if err := scanner.Err(); err != nil {
-		log.Println(err)
+	return wordCount, nil
+}
+
+// addEntry adds the entry senses and returns true if the entry is a new word.
+// Entries of sources with higher priority replace entries of lower priority ones.
+func (lu *langUpdater) addEntry(wd *wordDef, priority int) bool {
+	if len(wd.Senses) == 0 && wd.Word != "" {
+		return false
 	}
 
-	if wordCount == 0 {
-		return nil, fmt.Errorf("Dictionary in %s is empty\n", lang.Dict.Path)
+	var key string
+	if wd.Word != "" {
+		wk := wordKey{word: wd.Word}
+		if lu.parts {
+			key = wd.Pos + "/" + wd.Word
+			wk.part = wd.Pos
+		} else {
+			key = wd.Word
+		}
+		lu.entries[key] = wk
+	} else {
+		key = "redirect/" + wd.Title
+		title := strings.ToLower(wd.Title)
+		if _, exist := lu.redirects[title]; !exist && wd.Redirect != "" {
+			lu.redirects[title] = wd.Redirect
+		}
+	}
+
+	gloss, exist := lu.allDefs[key]
+	if exist && gloss.priority == priority {
+		gloss.addSenses(wd, lu.targets)
+		return false
+	}
+	if exist && gloss.priority > priority {
+		// sources without translations still can be complemented by others
+		gloss.addEntryTranslations(wd, lu.targets)
+		return false
 	}
 
-	fmt.Printf("Loaded %d words.\n", wordCount)
+	gloss = newGlossary(wd, priority, lu.targets)
 
-	lu := langUpdater{
-		db:      db,
-		langID:  lang.ID,
-		allDefs: allDefs,
+	lu.setGlossary(key, gloss)
+	lu.setGlossary("low/"+strings.ToLower(key), gloss)
+	if wd.Word != "" && lu.parts {
+		key = "any-pos/" + wd.Word
+		lu.setGlossary(key, gloss)
+		lu.setGlossary("low/"+strings.ToLower(key), gloss)
 	}
 
-	return &lu, err
+	return !exist
 }
 
-func (lu langUpdater) updateWordPack(pack WordPackConfig) error {
-	fmt.Printf("Updating word pack %s/%s...\n", lu.langID, pack.ID)
+func (lu *langUpdater) setGlossary(key string, gloss *glossary) {
+	old, exist := lu.allDefs[key]
+	if !exist || old.priority <= gloss.priority {
+		lu.allDefs[key] = gloss
+	}
+}
 
-	file, err := os.Open(pack.Path)
-	if err != nil {
-		return err
+// missingLinks returns redirect targets and lemmas of word forms which are not loaded yet.
+func (lu *langUpdater) missingLinks() map[string]bool {
+	loaded := make(map[string]bool)
+	for key := range lu.allDefs {
+		if strings.HasPrefix(key, "low/") {
+			continue
+		}
+		_, word, found := strings.Cut(key, "/")
+		if !found || !lu.parts {
+			word = key
+		}
+		loaded[strings.ToLower(word)] = true
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			log.Println(err)
+
+	missing := make(map[string]bool)
+	addMissing := func(word string) {
+		word = strings.ToLower(word)
+		if !loaded[word] {
+			missing[word] = true
 		}
-	}(file)
+	}
 
-	words := make([]string, 0, 200)
+	for key, gloss := range lu.allDefs {
+		for _, lemma := range gloss.lemmas {
+			addMissing(lemma)
+		}
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		word := strings.TrimSpace(scanner.Text())
-		if word != "" {
-			words = append(words, word)
+		if strings.HasPrefix(key, "redirect/") && gloss.redirect != "" {
+			addMissing(gloss.redirect)
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
+	return missing
+}
+
+func (lu *langUpdater) updateWordPack(pack WordPackConfig) error {
+	fmt.Printf("Updating word pack %s/%s...\n", lu.langID, pack.ID)
+
+	words, err := croc.ReadWords(pack.Path)
+	if err != nil {
 		return err
 	}
 
@@ -229,50 +628,191 @@ func (lu langUpdater) updateWordPack(pack WordPackConfig) error {
 		return fmt.Errorf("word pack in %s is empty", pack.Path)
 	}
 
-	tx, err := lu.db.Begin(true)
+	for _, word := range words {
+		lu.packWords[wordKey{part: pack.Part, word: word}] = true
+	}
+
+	// the transaction of the dry run is read-only
+	tx, err := lu.db.Begin(!lu.opts.DryRun)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	bkt, err := tx.CreateBucketIfNotExists([]byte(lu.langID))
+	bkt, err := bucket(tx, lu.langID, pack.Part)
 	if err != nil {
 		return err
 	}
 
-	if pack.Part != "" {
-		bkt, err = bkt.CreateBucketIfNotExists([]byte(pack.Part))
-		if err != nil {
-			return err
-		}
+	trBkt, err := bucket(tx, croc.TranslationsBucket, lu.langID, pack.Part)
+	if err != nil {
+		return err
 	}
 
-	var updated, notFound int
+	var updated, notFound, translated int
 
 	for _, word := range words {
-		def, err := lu.findDefinition(word, pack.Part)
+		gloss, err := lu.findGlossary(word, pack.Part)
 		if err != nil {
 			log.Println(err)
 			notFound++
+
+			// the definition was removed from the dictionaries
+			if lu.opts.Prune && getValue(bkt, word) != nil {
+				err = lu.removeDefinition(bkt, trBkt, pack, word)
+				if err != nil {
+					return err
+				}
+			}
 			continue
 		}
 
-		err = bkt.Put([]byte(word), []byte(def))
+		entry, err := lu.buildEntry(word, pack.Part, gloss, &lu.sanitized)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		action := changeAdded
+		if old := getValue(bkt, word); bytes.Equal(old, entry) {
+			action = ""
+		} else if old != nil {
+			action = changeChanged
+		}
+
+		err = put(bkt, word, entry)
 		if err != nil {
 			log.Println(err)
 			continue
 		}
 
 		updated++
+		if action != "" {
+			lu.addChange(pack.ID, pack.Part, "", word, action)
+		}
+		lu.stored = append(lu.stored, wordKey{part: pack.Part, word: word})
+
+		for target, trs := range gloss.translations {
+			targetBkt, err := bucket(tx, croc.TranslationsBucket, lu.langID, pack.Part, target)
+			if err != nil {
+				return err
+			}
+
+			err = put(targetBkt, word, []byte(strings.Join(trs, ", ")))
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+		}
+
+		if len(gloss.translations) > 0 {
+			translated++
+		}
+	}
+
+	fmt.Printf("Updated: %d. Translated: %d. Not found: %d. Total: %d.\n",
+		updated, translated, notFound, len(words))
+	lu.printChanges(pack.ID)
+
+	return lu.commit(tx)
+}
+
+// updateIndex writes entries of all loaded words as they are spelled in the dictionaries
+// and rebuilds lookup indexes of the language used if the word of the pack is not found as is.
+func (lu *langUpdater) updateIndex() error {
+	fmt.Printf("Updating lookup index of %s...\n", lu.langID)
+
+	tx, err := lu.db.Begin(!lu.opts.DryRun)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	lowKeys := make(map[string][]string)
+	addKey := func(wk wordKey) {
+		low := strings.ToLower(wk.word)
+		key := wk.part + "\t" + wk.word
+		if !slices.Contains(lowKeys[low], key) {
+			lowKeys[low] = append(lowKeys[low], key)
+		}
+	}
+
+	for key, wk := range lu.entries {
+		entry, err := lu.buildEntry(wk.word, wk.part, lu.allDefs[key], &sanitizeStats{})
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		partBkt, err := bucket(tx, lu.langID, wk.part)
+		if err != nil {
+			return err
+		}
+
+		err = put(partBkt, wk.word, entry)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		addKey(wk)
+	}
+
+	for _, wk := range lu.stored {
+		addKey(wk)
+	}
+
+	if !tx.Writable() {
+		fmt.Printf("Indexed: %d words. Redirects: %d.\n", len(lowKeys), len(lu.redirects))
+		return nil
+	}
+
+	idxBkt, err := tx.CreateBucketIfNotExists([]byte(croc.IndexBucket))
+	if err != nil {
+		return err
+	}
+
+	err = idxBkt.DeleteBucket([]byte(lu.langID))
+	if err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+		return err
+	}
+
+	langIdx, err := idxBkt.CreateBucket([]byte(lu.langID))
+	if err != nil {
+		return err
+	}
+
+	lowBkt, err := langIdx.CreateBucket([]byte(croc.LowercaseIndex))
+	if err != nil {
+		return err
+	}
+
+	for low, keys := range lowKeys {
+		sort.Strings(keys)
+		err = lowBkt.Put([]byte(low), []byte(strings.Join(keys, "\n")))
+		if err != nil {
+			return err
+		}
+	}
+
+	redirectBkt, err := langIdx.CreateBucket([]byte(croc.RedirectIndex))
+	if err != nil {
+		return err
+	}
+
+	for word, target := range lu.redirects {
+		err = redirectBkt.Put([]byte(word), []byte(target))
+		if err != nil {
+			return err
+		}
 	}
 
-	fmt.Printf("Updated: %d. Not found: %d. Total: %d.\n",
-		updated, notFound, len(words))
+	fmt.Printf("Indexed: %d words. Redirects: %d.\n", len(lowKeys), len(lu.redirects))
 
-	return tx.Commit()
+	return lu.commit(tx)
 }
 
-func (lu langUpdater) findDefinition(query, pos string) (string, error) {
+func (lu *langUpdater) findGlossary(query, pos string) (*glossary, error) {
 	var key string
 	if pos == "" {
 		key = query
@@ -306,8 +846,8 @@ func (lu langUpdater) findDefinition(query, pos string) (string, error) {
 		}
 	}
 	if !found {
-		return "", fmt.Errorf("definition of word '%s' not found", query)
+		return nil, fmt.Errorf("definition of word '%s' not found", query)
 	}
 
-	return gloss.getDefinition(), nil
+	return gloss, nil
 }
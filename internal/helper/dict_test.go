@@ -0,0 +1,113 @@
+package helper
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newTestLangUpdater(parts bool, targets ...string) *langUpdater {
+	lu := &langUpdater{
+		langID:    "en",
+		parts:     parts,
+		allDefs:   make(map[string]*glossary),
+		entries:   make(map[string]wordKey),
+		redirects: make(map[string]string),
+		targets:   make(map[string]bool),
+	}
+	for _, target := range targets {
+		lu.targets[target] = true
+	}
+
+	return lu
+}
+
+func glossSenses(gloss *glossary) []string {
+	senses := make([]string, 0, len(gloss.senses))
+	for _, sense := range gloss.senses {
+		senses = append(senses, sense.Gloss)
+	}
+	return senses
+}
+
+func TestAddEntry(t *testing.T) {
+	translated := func(wd *wordDef, code, word string) *wordDef {
+		wd.Translations = append(wd.Translations, translation{Code: code, Word: word})
+		return wd
+	}
+
+	tests := []struct {
+		name         string
+		entries      []*wordDef
+		priorities   []int
+		senses       []string
+		translations map[string][]string
+	}{
+		{
+			name:       "the same priority adds senses",
+			entries:    []*wordDef{newWordDef("cat", "", "a pet"), newWordDef("cat", "", "a feline")},
+			priorities: []int{1, 1},
+			senses:     []string{"a pet", "a feline"},
+		},
+		{
+			name:       "higher priority replaces senses",
+			entries:    []*wordDef{newWordDef("cat", "", "a pet"), newWordDef("cat", "", "a feline")},
+			priorities: []int{1, 2},
+			senses:     []string{"a feline"},
+		},
+		{
+			name: "lower priority adds only translations",
+			entries: []*wordDef{
+				newWordDef("cat", "", "a feline"),
+				translated(newWordDef("cat", "", "a pet"), "ru", "кот"),
+			},
+			priorities:   []int{2, 1},
+			senses:       []string{"a feline"},
+			translations: map[string][]string{"ru": {"кот"}},
+		},
+		{
+			name: "translations into other languages are skipped",
+			entries: []*wordDef{
+				translated(translated(newWordDef("cat", "", "a pet"), "de", "Katze"), "ru", "кошка"),
+			},
+			priorities:   []int{1},
+			senses:       []string{"a pet"},
+			translations: map[string][]string{"ru": {"кошка"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lu := newTestLangUpdater(false, "ru")
+			for i, wd := range tt.entries {
+				added := lu.addEntry(wd, tt.priorities[i])
+				require.Equal(t, i == 0, added)
+			}
+
+			gloss, ok := lu.allDefs["cat"]
+			require.True(t, ok)
+			require.Equal(t, tt.senses, glossSenses(gloss))
+			if tt.translations == nil {
+				tt.translations = map[string][]string{}
+			}
+			require.Equal(t, tt.translations, gloss.translations)
+			require.Same(t, gloss, lu.allDefs["low/cat"])
+		})
+	}
+}
+
+func TestAddEntryParts(t *testing.T) {
+	lu := newTestLangUpdater(true)
+	require.True(t, lu.addEntry(newWordDef("Run", "verb", "to move fast"), 1))
+	require.True(t, lu.addEntry(newWordDef("Run", "noun", "a fast movement"), 2))
+	require.False(t, lu.addEntry(newWordDef("Run", "noun", ""), 2))
+
+	require.Equal(t, []string{"to move fast"}, glossSenses(lu.allDefs["verb/Run"]))
+	require.Equal(t, []string{"a fast movement"}, glossSenses(lu.allDefs["low/noun/run"]))
+	// the entry of the higher priority source is found for any part of speech
+	require.Equal(t, []string{"a fast movement"}, glossSenses(lu.allDefs["any-pos/Run"]))
+	require.Equal(t, wordKey{part: "verb", word: "Run"}, lu.entries["verb/Run"])
+
+	redirect := &wordDef{Title: "Ran", Redirect: "run"}
+	require.True(t, lu.addEntry(redirect, 1))
+	require.Equal(t, "run", lu.redirects["ran"])
+}
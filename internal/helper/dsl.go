@@ -0,0 +1,168 @@
+package helper
+
+import (
+	"bufio"
+	"fmt"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// dslSource reads ABBYY Lingvo DSL glossaries in UTF-8 or UTF-16 with BOM.
+// A card is one or more headword lines followed by indented body lines.
+type dslSource struct {
+	paths []string
+}
+
+type dslCard struct {
+	headwords []string
+	senses    []string
+	hasBody   bool
+}
+
+func (src *dslSource) Read(needed func(string) bool, add func(*wordDef), st *loadStats) error {
+	for _, path := range src.paths {
+		err := readDSL(path, needed, add, st)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+func readDSL(path string, needed func(string) bool, add func(*wordDef), st *loadStats) error {
+	file, err := openDump(path)
+	if err != nil {
+		return err
+	}
+	defer func(file io.Closer) {
+		err := file.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(file)
+
+	// the BOM override detects UTF-16 and strips the UTF-8 BOM, UTF-8 is the default
+	decoder := unicode.BOMOverride(unicode.UTF8.NewDecoder())
+	scanner := bufio.NewScanner(transform.NewReader(file, decoder))
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, maxLineSize)
+
+	card := &dslCard{}
+	flush := func() {
+		if len(card.headwords) > 0 && len(card.senses) == 0 {
+			st.malformed++
+		}
+
+		for _, hw := range card.headwords {
+			if needed(hw) && len(card.senses) > 0 {
+				add(newWordDef(hw, "", card.senses...))
+			}
+		}
+
+		card = &dslCard{}
+	}
+
+	for scanner.Scan() {
+		st.addLine()
+
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case strings.HasPrefix(line, "#") && len(card.headwords) == 0:
+			continue
+		case strings.TrimSpace(line) == "":
+			continue
+		case line[0] == ' ' || line[0] == '\t':
+			card.hasBody = true
+			var hw string
+			if len(card.headwords) > 0 {
+				hw = card.headwords[0]
+			}
+			sense := cleanDSLBody(line, hw)
+			if sense != "" {
+				card.senses = append(card.senses, sense)
+			}
+		default:
+			if card.hasBody {
+				flush()
+			}
+			card.headwords = append(card.headwords, dslHeadwords(line)...)
+		}
+	}
+	flush()
+
+	return scanner.Err()
+}
+
+var (
+	dslComment  = regexp.MustCompile(`\{\{.*?\}\}`)
+	dslExample  = regexp.MustCompile(`\[ex\].*?\[/ex\]`)
+	dslPosLabel = regexp.MustCompile(`\[p\].*?\[/p\]`)
+	// dslTag also matches escaped characters to keep escaped brackets
+	dslTag      = regexp.MustCompile(`\\.|\[/?[a-z][^\]]*\]`)
+	dslUnsorted = regexp.MustCompile(`\{[^}]*\}`)
+	dslOptional = regexp.MustCompile(`\(([^)]*)\)`)
+	dslSpaces   = regexp.MustCompile(`\s+`)
+)
+
+// cleanDSLBody strips markup, examples and part of speech labels from the body line.
+func cleanDSLBody(line, headword string) string {
+	line = dslComment.ReplaceAllString(line, "")
+	line = dslExample.ReplaceAllString(line, "")
+	line = dslPosLabel.ReplaceAllString(line, "")
+	line = dslTag.ReplaceAllStringFunc(line, func(tag string) string {
+		if strings.HasPrefix(tag, `\`) {
+			return tag
+		}
+		return ""
+	})
+	line = unescapeDSL(line, headword)
+	return strings.TrimSpace(dslSpaces.ReplaceAllString(line, " "))
+}
+
+// unescapeDSL replaces the unescaped tilde with the headword and removes escaping backslashes.
+func unescapeDSL(text, headword string) string {
+	var res strings.Builder
+	escaped := false
+	for _, r := range text {
+		switch {
+		case escaped:
+			res.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '~':
+			res.WriteString(headword)
+		default:
+			res.WriteRune(r)
+		}
+	}
+
+	return res.String()
+}
+
+// dslHeadwords returns variants of the headword with and without optional parts in parentheses.
+func dslHeadwords(line string) []string {
+	line = dslUnsorted.ReplaceAllString(line, "")
+
+	full := dslOptional.ReplaceAllString(line, "$1")
+	short := dslOptional.ReplaceAllString(line, "")
+
+	full = strings.TrimSpace(dslSpaces.ReplaceAllString(unescapeDSL(full, ""), " "))
+	short = strings.TrimSpace(dslSpaces.ReplaceAllString(unescapeDSL(short, ""), " "))
+
+	// the short variant goes first as the tilde in the card body refers to it
+	headwords := make([]string, 0, 2)
+	if short != "" {
+		headwords = append(headwords, short)
+	}
+	if full != "" && full != short {
+		headwords = append(headwords, full)
+	}
+
+	return headwords
+}
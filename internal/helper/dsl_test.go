@@ -0,0 +1,62 @@
+package helper
+
+import (
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/unicode"
+	"testing"
+)
+
+func TestDSLSource(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		defs      map[string][]string
+		malformed int
+	}{
+		{
+			name: "cards",
+			content: "#NAME \"Test\"\n#INDEX_LANGUAGE \"English\"\n\n" +
+				"cat\n\t[m1][p]n[/p] a small [i]furry[/i] animal[/m]\n\t[m1][ex]~ and dog[/ex][/m]\n" +
+				"dog\n\t[m1]a loyal animal {{comment}}[/m]\n",
+			defs: map[string][]string{"cat": {"a small furry animal"}, "dog": {"a loyal animal"}},
+		},
+		{
+			name:    "several headwords",
+			content: "colour\ncolor\n\tthe ~ of the sky\n",
+			defs:    map[string][]string{"colour": {"the colour of the sky"}, "color": {"the colour of the sky"}},
+		},
+		{
+			name:    "optional parts and escapes",
+			content: "look (up)\n\tto search for \\[something\\]\n",
+			defs: map[string][]string{
+				"look":    {"to search for [something]"},
+				"look up": {"to search for [something]"},
+			},
+		},
+		{
+			name:      "card without body",
+			content:   "cat\n\t[ex]~ and dog[/ex]\ndog\n\ta loyal animal\n",
+			defs:      map[string][]string{"dog": {"a loyal animal"}},
+			malformed: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, "dict.dsl", []byte(tt.content))
+			defs, st := readTestSource(t, &dslSource{paths: []string{path}})
+			require.Equal(t, tt.defs, defs)
+			require.Equal(t, tt.malformed, st.malformed)
+		})
+	}
+}
+
+func TestDSLSourceUTF16(t *testing.T) {
+	encoder := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder()
+	content, err := encoder.Bytes([]byte("кот\n\tдомашнее животное\n"))
+	require.NoError(t, err)
+
+	path := writeTestFile(t, "dict.dsl", content)
+	defs, _ := readTestSource(t, &dslSource{paths: []string{path}})
+	require.Equal(t, map[string][]string{"кот": {"домашнее животное"}}, defs)
+}
@@ -0,0 +1,93 @@
+package helper
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// stdinPath means the dump is read from the standard input.
+const stdinPath = "-"
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+)
+
+// dumpPaths expands the glob pattern into the sorted list of dump files.
+func dumpPaths(pattern string) ([]string, error) {
+	if pattern == stdinPath {
+		return []string{stdinPath}, nil
+	}
+
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("dictionary file %s not found", pattern)
+	}
+
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+type dumpReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (dr *dumpReader) Close() error {
+	var err error
+	for i := len(dr.closers) - 1; i >= 0; i-- {
+		if cerr := dr.closers[i].Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// openDump opens the dump file, or the standard input, and transparently decompresses gzip and bzip2.
+func openDump(path string) (io.ReadCloser, error) {
+	dr := &dumpReader{}
+
+	var file io.Reader
+	if path == stdinPath {
+		file = os.Stdin
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		file = f
+		dr.closers = append(dr.closers, f)
+	}
+
+	buffered := bufio.NewReader(file)
+	magic, _ := buffered.Peek(len(bzip2Magic))
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			_ = dr.Close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		dr.Reader = gz
+		dr.closers = append(dr.closers, gz)
+	case bytes.HasPrefix(magic, bzip2Magic):
+		dr.Reader = bzip2.NewReader(buffered)
+	default:
+		dr.Reader = buffered
+	}
+
+	return dr, nil
+}
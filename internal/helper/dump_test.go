@@ -0,0 +1,105 @@
+package helper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/stretchr/testify/require"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+const testDump = "cat\tan animal\n"
+
+// testDumpBzip2 is testDump compressed by bzip2, which has no writer in the standard library.
+var testDumpBzip2 = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x15, 0x8d, 0x77, 0x1e, 0x00, 0x00, 0x03, 0xd1,
+	0x80, 0x00, 0x30, 0x40, 0x00, 0x28, 0x27, 0x04, 0x00, 0x20, 0x00, 0x22, 0x00, 0x36, 0xa1, 0x00, 0x30, 0x38,
+	0x2f, 0x57, 0x62, 0x21, 0xfc, 0x5d, 0xc9, 0x14, 0xe1, 0x42, 0x40, 0x56, 0x35, 0xdc, 0x78,
+}
+
+func gzipTestDump(t *testing.T) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(testDump))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	return buf.Bytes()
+}
+
+func readTestDump(t *testing.T, path string) string {
+	file, err := openDump(path)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, file.Close()) }()
+
+	data, err := io.ReadAll(file)
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+func TestOpenDump(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"plain", []byte(testDump), testDump},
+		{"gzip", gzipTestDump(t), testDump},
+		{"bzip2", testDumpBzip2, testDump},
+		// the compression is detected by the content, not the extension
+		{"plain.gz", []byte(testDump), testDump},
+		{"short", []byte("B"), "B"},
+		{"empty", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, tt.name, tt.content)
+			require.Equal(t, tt.want, readTestDump(t, path))
+		})
+	}
+
+	path := writeTestFile(t, "broken.gz", []byte{0x1f, 0x8b, 0x00})
+	_, err := openDump(path)
+	require.Error(t, err)
+
+	_, err = openDump(filepath.Join(t.TempDir(), "missing"))
+	require.Error(t, err)
+}
+
+func TestOpenDumpStdin(t *testing.T) {
+	setTestStdin(t, writeTestFile(t, "dump.gz", gzipTestDump(t)))
+
+	paths, err := dumpPaths(stdinPath)
+	require.NoError(t, err)
+	require.Equal(t, []string{stdinPath}, paths)
+	require.Equal(t, testDump, readTestDump(t, stdinPath))
+}
+
+func TestDumpPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"dump-10.jsonl", "dump-02.jsonl", "dump-01.jsonl", "other.txt"} {
+		writeTestFileAt(t, filepath.Join(dir, name), []byte(testDump))
+	}
+
+	paths, err := dumpPaths(filepath.Join(dir, "dump-*.jsonl"))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "dump-01.jsonl"),
+		filepath.Join(dir, "dump-02.jsonl"),
+		filepath.Join(dir, "dump-10.jsonl"),
+	}, paths)
+
+	paths, err = dumpPaths(filepath.Join(dir, "other.txt"))
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "other.txt")}, paths)
+
+	_, err = dumpPaths(filepath.Join(dir, "missing-*.jsonl"))
+	require.ErrorContains(t, err, "not found")
+	_, err = dumpPaths(filepath.Join(dir, "missing.jsonl"))
+	require.ErrorContains(t, err, "not found")
+	_, err = dumpPaths(filepath.Join(dir, "dump-[.jsonl"))
+	require.Error(t, err)
+}
@@ -0,0 +1,463 @@
+package helper
+
+import (
+	"bufio"
+	"crocodiler/internal/croc"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Reasons to leave the dictionary word out of the generated pack, in the order of checks.
+const (
+	rejectLength    = "length"
+	rejectSymbols   = "symbols"
+	rejectExcluded  = "excluded"
+	rejectFrequency = "frequency list"
+	rejectPart      = "part of speech"
+	rejectSenses    = "senses"
+	rejectLimit     = "pack size"
+)
+
+var rejectReasons = []string{
+	rejectLength, rejectSymbols, rejectExcluded, rejectFrequency, rejectPart, rejectSenses, rejectLimit,
+}
+
+// maxReportMissing limits the number of frequent words missing from the dictionaries listed in the report.
+const maxReportMissing = 100
+
+// partSenses is the number of senses of the word as the part of speech in the dictionary with the priority.
+type partSenses struct {
+	priority int
+	senses   int
+}
+
+type candidate struct {
+	parts map[string]*partSenses
+}
+
+// countSenses returns the number of senses of the parts of speech, or of all parts if parts are empty.
+func (c *candidate) countSenses(parts map[string]bool) (int, bool) {
+	var senses int
+	found := false
+	for part, ps := range c.parts {
+		if len(parts) > 0 && !parts[part] {
+			continue
+		}
+		senses += ps.senses
+		found = true
+	}
+
+	return senses, found
+}
+
+type packGenerator struct {
+	cfg      GenPackConfig
+	parts    map[string]bool
+	exclude  map[string]bool
+	freq     []string
+	ranks    map[string]int
+	rejected map[string]int
+}
+
+func newPackGenerator(cfg GenPackConfig) (*packGenerator, error) {
+	pg := &packGenerator{
+		cfg:      cfg,
+		parts:    make(map[string]bool),
+		exclude:  make(map[string]bool),
+		rejected: make(map[string]int),
+	}
+
+	if cfg.Path == "" {
+		return nil, errors.New("path of the generated word pack is not set")
+	}
+
+	for _, part := range cfg.Parts {
+		pg.parts[part] = true
+	}
+
+	if cfg.ExcludePath != "" {
+		words, err := croc.ReadWords(cfg.ExcludePath)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, word := range words {
+			pg.exclude[strings.ToLower(word)] = true
+		}
+	}
+
+	if cfg.FreqPath != "" {
+		freq, err := readFreqList(cfg.FreqPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.MaxRank > 0 && len(freq) > cfg.MaxRank {
+			freq = freq[:cfg.MaxRank]
+		}
+
+		pg.freq = freq
+		pg.ranks = make(map[string]int, len(freq))
+		for i, word := range freq {
+			pg.ranks[word] = i + 1
+		}
+	}
+
+	return pg, nil
+}
+
+// readFreqList returns lowercase words of the frequency list in the order of the list without duplicates.
+// A line is the word, optionally followed by its count after a tab or a space.
+func readFreqList(path string) ([]string, error) {
+	file, err := openDump(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func(file io.Closer) {
+		err := file.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(file)
+
+	var words []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		word, _, found := strings.Cut(line, "\t")
+		if !found {
+			fields := strings.Fields(line)
+			if len(fields) > 1 {
+				if _, err := strconv.ParseFloat(fields[len(fields)-1], 64); err == nil {
+					fields = fields[:len(fields)-1]
+				}
+			}
+			word = strings.Join(fields, " ")
+		}
+
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word == "" || seen[word] {
+			continue
+		}
+
+		seen[word] = true
+		words = append(words, word)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return words, nil
+}
+
+// checkWord returns the reason to leave the lowercase word out of the pack, or an empty string.
+// Only the word itself is checked here, its dictionary entries are checked by selectWords.
+func (pg *packGenerator) checkWord(word string) string {
+	size := utf8.RuneCountInString(word)
+	if size < pg.cfg.MinLen || (pg.cfg.MaxLen > 0 && size > pg.cfg.MaxLen) {
+		return rejectLength
+	}
+
+	if !isPlainWord(word) {
+		return rejectSymbols
+	}
+
+	if pg.exclude[word] {
+		return rejectExcluded
+	}
+
+	if pg.ranks != nil && pg.ranks[word] == 0 {
+		return rejectFrequency
+	}
+
+	return ""
+}
+
+// isPlainWord reports whether the word consists of letters separated by single spaces, hyphens or apostrophes.
+func isPlainWord(word string) bool {
+	afterLetter := false
+	for _, r := range word {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsMark(r):
+			afterLetter = true
+		case afterLetter && (r == ' ' || r == '-' || r == '\''):
+			afterLetter = false
+		default:
+			return false
+		}
+	}
+
+	return afterLetter
+}
+
+// selectWords returns the sorted words of the pack. The lowercase spelling is preferred if the dictionary has several.
+func (pg *packGenerator) selectWords(cands map[string]*candidate) []string {
+	forms := make(map[string]string)
+	senses := make(map[string]int)
+	for word, cand := range cands {
+		low := strings.ToLower(word)
+		if pg.checkWord(low) != "" {
+			// already counted while reading the dictionaries
+			continue
+		}
+
+		cnt, found := cand.countSenses(pg.parts)
+		if !found {
+			pg.rejected[rejectPart]++
+			continue
+		}
+
+		if cnt < max(pg.cfg.MinSenses, 1) {
+			pg.rejected[rejectSenses]++
+			continue
+		}
+
+		prev, exist := forms[low]
+		if !exist || word == low || (prev != low && word < prev) {
+			forms[low] = word
+			senses[low] = cnt
+		}
+	}
+
+	lows := make([]string, 0, len(forms))
+	for low := range forms {
+		lows = append(lows, low)
+	}
+
+	// the most frequent words, or the words with more senses, are kept if the pack is too large
+	sort.Slice(lows, func(i, j int) bool {
+		if pg.ranks != nil && pg.ranks[lows[i]] != pg.ranks[lows[j]] {
+			return pg.ranks[lows[i]] < pg.ranks[lows[j]]
+		}
+		if senses[lows[i]] != senses[lows[j]] {
+			return senses[lows[i]] > senses[lows[j]]
+		}
+		return lows[i] < lows[j]
+	})
+
+	if pg.cfg.MaxWords > 0 && len(lows) > pg.cfg.MaxWords {
+		pg.rejected[rejectLimit] += len(lows) - pg.cfg.MaxWords
+		lows = lows[:pg.cfg.MaxWords]
+	}
+
+	words := make([]string, 0, len(lows))
+	for _, low := range lows {
+		words = append(words, forms[low])
+	}
+	sort.Strings(words)
+
+	return words
+}
+
+func (pg *packGenerator) reportPath() string {
+	return strings.TrimSuffix(pg.cfg.Path, filepath.Ext(pg.cfg.Path)) + ".report"
+}
+
+// writePack writes the words as the plain text word pack and the coverage report next to it.
+func (pg *packGenerator) writePack(words []string, dictWords map[string]bool) error {
+	err := os.MkdirAll(filepath.Dir(pg.cfg.Path), 0755)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(pg.cfg.Path, []byte(strings.Join(words, "\n")+"\n"), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(pg.reportPath(), []byte(pg.report(words, dictWords)), 0644)
+}
+
+// report describes how many dictionary words are filtered out and how much of the frequency list is covered.
+func (pg *packGenerator) report(words []string, dictWords map[string]bool) string {
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "Pack: %s\n", pg.cfg.Path)
+	_, _ = fmt.Fprintf(&sb, "Dictionary words: %d\n", len(dictWords))
+	_, _ = fmt.Fprintf(&sb, "Pack words: %d\n", len(words))
+	_, _ = fmt.Fprintln(&sb, "Filtered out by:")
+	for _, reason := range rejectReasons {
+		_, _ = fmt.Fprintf(&sb, "\t%s: %d\n", reason, pg.rejected[reason])
+	}
+
+	if pg.freq == nil {
+		return sb.String()
+	}
+
+	inPack := make(map[string]bool, len(words))
+	for _, word := range words {
+		inPack[strings.ToLower(word)] = true
+	}
+
+	var found, packed int
+	var missing []string
+	for _, word := range pg.freq {
+		if _, exist := dictWords[word]; !exist {
+			missing = append(missing, word)
+			continue
+		}
+
+		found++
+		if inPack[word] {
+			packed++
+		}
+	}
+
+	total := len(pg.freq)
+	_, _ = fmt.Fprintf(&sb, "Frequency list: %d words, %d (%.1f%%) in dictionaries, %d (%.1f%%) in the pack.\n",
+		total, found, percent(found, total), packed, percent(packed, total))
+
+	if len(missing) > 0 {
+		_, _ = fmt.Fprintf(&sb, "Most frequent words missing from dictionaries (%d):\n", len(missing))
+		for _, word := range missing[:min(len(missing), maxReportMissing)] {
+			_, _ = fmt.Fprintf(&sb, "\t%s\n", word)
+		}
+	}
+
+	return sb.String()
+}
+
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return float64(part) * 100 / float64(total)
+}
+
+// GeneratePacks writes word packs of the languages built from their dictionaries by gen_packs filters.
+func GeneratePacks(cfgPath string) {
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, lang := range cfg.Languages {
+		if len(lang.GenPacks) == 0 {
+			continue
+		}
+
+		err := generateLangPacks(lang)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func generateLangPacks(lang LanguageConfig) error {
+	gens := make([]*packGenerator, 0, len(lang.GenPacks))
+	for _, packCfg := range lang.GenPacks {
+		pg, err := newPackGenerator(packCfg)
+		if err != nil {
+			return err
+		}
+		gens = append(gens, pg)
+	}
+
+	dictWords, cands, err := loadCandidates(lang, gens)
+	if err != nil {
+		return err
+	}
+
+	for _, pg := range gens {
+		words := pg.selectWords(cands)
+		err := pg.writePack(words, dictWords)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		fmt.Printf("Generated word pack %s with %d words, report: %s.\n",
+			pg.cfg.Path, len(words), pg.reportPath())
+	}
+
+	return nil
+}
+
+// loadCandidates reads the language dictionaries and returns all lowercase dictionary words
+// and the entries of the words passing checkWord of at least one pack.
+func loadCandidates(lang LanguageConfig, gens []*packGenerator) (map[string]bool, map[string]*candidate, error) {
+	dicts := lang.getDicts()
+	if len(dicts) == 0 {
+		return nil, nil, fmt.Errorf("language %s has no dictionaries", lang.ID)
+	}
+
+	// dictWords tells whether the dictionary word is needed by any pack
+	dictWords := make(map[string]bool)
+	cands := make(map[string]*candidate)
+	for _, dict := range dicts {
+		src, err := newDictSource(dict, lang.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		fmt.Printf("Reading %s dictionary %s...\n", dict.getFormat(), dict.Path)
+
+		var st loadStats
+		needed := func(word string) bool {
+			low := strings.ToLower(word)
+			ok, exist := dictWords[low]
+			if exist {
+				return ok
+			}
+
+			for _, pg := range gens {
+				reason := pg.checkWord(low)
+				if reason == "" {
+					ok = true
+				} else {
+					pg.rejected[reason]++
+				}
+			}
+
+			dictWords[low] = ok
+			return ok
+		}
+		add := func(wd *wordDef) {
+			senses := len(newGlossary(wd, dict.Priority, nil).senses)
+			if wd.Word == "" || senses == 0 {
+				return
+			}
+
+			cand, exist := cands[wd.Word]
+			if !exist {
+				cand = &candidate{parts: make(map[string]*partSenses)}
+				cands[wd.Word] = cand
+				st.words++
+			}
+
+			// entries of sources with higher priority win as in the dictionary update
+			ps, exist := cand.parts[wd.Pos]
+			switch {
+			case !exist || ps.priority < dict.Priority:
+				cand.parts[wd.Pos] = &partSenses{priority: dict.Priority, senses: senses}
+			case ps.priority == dict.Priority:
+				ps.senses += senses
+			}
+		}
+
+		err = src.Read(needed, add, &st)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		fmt.Printf("Lines: %d. Malformed: %d. Other languages: %d. Words: %d.\n",
+			st.lines, st.malformed, st.otherLang, st.words)
+	}
+
+	return dictWords, cands, nil
+}
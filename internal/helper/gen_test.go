@@ -0,0 +1,157 @@
+package helper
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadFreqList(t *testing.T) {
+	path := writeTestFile(t, "freq.txt", []byte("# word count\nThe\t100\nof 50\n\nice cream 20\nthe\t10\nNew York\nrock'n'roll 1.5e3\n"))
+
+	words, err := readFreqList(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"the", "of", "ice cream", "new york", "rock'n'roll"}, words)
+
+	_, err = readFreqList(filepath.Join(t.TempDir(), "missing.txt"))
+	require.Error(t, err)
+}
+
+func TestCheckWord(t *testing.T) {
+	dir := t.TempDir()
+	excludePath := filepath.Join(dir, "exclude.txt")
+	writeTestFileAt(t, excludePath, []byte("Dog\n"))
+	freqPath := filepath.Join(dir, "freq.txt")
+	writeTestFileAt(t, freqPath, []byte("cat\ndog\nice cream\nfox\nowl\n"))
+
+	pg, err := newPackGenerator(GenPackConfig{
+		Path:        filepath.Join(dir, "pack.txt"),
+		MinLen:      3,
+		MaxLen:      9,
+		FreqPath:    freqPath,
+		MaxRank:     4,
+		ExcludePath: excludePath,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"cat", "dog", "ice cream", "fox"}, pg.freq)
+
+	tests := []struct {
+		word   string
+		reason string
+	}{
+		{"cat", ""},
+		{"ice cream", ""},
+		{"ox", rejectLength},
+		{"ice creams", rejectLength},
+		{"cat!", rejectSymbols},
+		{"dog", rejectExcluded},
+		{"owl", rejectFrequency},
+		{"horse", rejectFrequency},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.reason, pg.checkWord(tt.word), tt.word)
+	}
+
+	_, err = newPackGenerator(GenPackConfig{})
+	require.Error(t, err)
+}
+
+func TestIsPlainWord(t *testing.T) {
+	require.True(t, isPlainWord("cat"))
+	require.True(t, isPlainWord("ice cream"))
+	require.True(t, isPlainWord("rock'n'roll"))
+	require.True(t, isPlainWord("café"))
+	require.False(t, isPlainWord(""))
+	require.False(t, isPlainWord("ice  cream"))
+	require.False(t, isPlainWord("-cat"))
+	require.False(t, isPlainWord("cat-"))
+	require.False(t, isPlainWord("cat2"))
+}
+
+func testCandidate(parts map[string]int) *candidate {
+	cand := &candidate{parts: make(map[string]*partSenses)}
+	for part, senses := range parts {
+		cand.parts[part] = &partSenses{senses: senses}
+	}
+
+	return cand
+}
+
+func TestSelectWords(t *testing.T) {
+	cands := map[string]*candidate{
+		"cat":   testCandidate(map[string]int{"noun": 2}),
+		"Cat":   testCandidate(map[string]int{"noun": 3}),
+		"BOB":   testCandidate(map[string]int{"noun": 1}),
+		"Bob":   testCandidate(map[string]int{"noun": 1}),
+		"run":   testCandidate(map[string]int{"verb": 4}),
+		"dog":   testCandidate(map[string]int{"noun": 1, "verb": 1}),
+		"ox":    testCandidate(map[string]int{"noun": 5}),
+		"horse": testCandidate(map[string]int{"noun": 1}),
+	}
+
+	pg, err := newPackGenerator(GenPackConfig{Path: "pack.txt", Parts: []string{"noun"}, MinLen: 3})
+	require.NoError(t, err)
+	// the lowercase form wins, otherwise the first form in the byte order
+	require.Equal(t, []string{"BOB", "cat", "dog", "horse"}, pg.selectWords(cands))
+	require.Equal(t, 1, pg.rejected[rejectPart])
+	for i := 0; i < 10; i++ {
+		require.Equal(t, []string{"BOB", "cat", "dog", "horse"}, pg.selectWords(cands))
+	}
+
+	pg, err = newPackGenerator(GenPackConfig{Path: "pack.txt", MinLen: 3, MinSenses: 2})
+	require.NoError(t, err)
+	require.Equal(t, []string{"cat", "dog", "run"}, pg.selectWords(cands))
+	require.Equal(t, 3, pg.rejected[rejectSenses])
+
+	// the words with more senses are kept
+	pg, err = newPackGenerator(GenPackConfig{Path: "pack.txt", MinLen: 3, MaxWords: 2})
+	require.NoError(t, err)
+	require.Equal(t, []string{"cat", "run"}, pg.selectWords(cands))
+	require.Equal(t, 3, pg.rejected[rejectLimit])
+
+	// the most frequent words are kept
+	freqPath := writeTestFile(t, "freq.txt", []byte("horse\nbob\ndog\ncat\n"))
+	pg, err = newPackGenerator(GenPackConfig{Path: "pack.txt", MinLen: 3, FreqPath: freqPath, MaxWords: 2})
+	require.NoError(t, err)
+	require.Equal(t, []string{"BOB", "horse"}, pg.selectWords(cands))
+}
+
+func TestGenerateLangPacks(t *testing.T) {
+	dir := t.TempDir()
+	dictPath := filepath.Join(dir, "dict.tsv")
+	writeTestFileAt(t, dictPath, []byte("cat\tnoun\ta small animal\ncat\tnoun\ta spiteful woman\n"+
+		"dog\tnoun\ta loyal animal\nrun\tverb\tto move fast\nox\tnoun\ta bovine animal\n"))
+	freqPath := filepath.Join(dir, "freq.txt")
+	writeTestFileAt(t, freqPath, []byte("the\ncat\ndog\nrun\nunicorn\n"))
+
+	packPath := filepath.Join(dir, "packs", "nouns.txt")
+	lang := LanguageConfig{
+		ID:    "en",
+		Dicts: []DictConfig{{Path: dictPath, Format: formatTSV, Parts: true}},
+		GenPacks: []GenPackConfig{{
+			Path:      packPath,
+			Parts:     []string{"noun"},
+			MinLen:    3,
+			MinSenses: 2,
+			FreqPath:  freqPath,
+		}},
+	}
+	require.NoError(t, generateLangPacks(lang))
+
+	data, err := os.ReadFile(packPath)
+	require.NoError(t, err)
+	require.Equal(t, "cat\n", string(data))
+
+	data, err = os.ReadFile(filepath.Join(dir, "packs", "nouns.report"))
+	require.NoError(t, err)
+	report := string(data)
+	require.Contains(t, report, "Dictionary words: 4\n")
+	require.Contains(t, report, "Pack words: 1\n")
+	require.Contains(t, report, "\tlength: 1\n")
+	require.Contains(t, report, "\tpart of speech: 1\n")
+	require.Contains(t, report, "\tsenses: 1\n")
+	require.Contains(t, report, "Frequency list: 5 words, 3 (60.0%) in dictionaries, 1 (20.0%) in the pack.\n")
+	require.True(t, strings.HasSuffix(report, "Most frequent words missing from dictionaries (2):\n\tthe\n\tunicorn\n"))
+}
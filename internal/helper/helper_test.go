@@ -0,0 +1,51 @@
+package helper
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, name string, content []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	writeTestFileAt(t, path, content)
+
+	return path
+}
+
+func writeTestFileAt(t *testing.T, path string, content []byte) {
+	err := os.WriteFile(path, content, 0644)
+	require.NoError(t, err)
+}
+
+// setTestStdin replaces the standard input with the file until the test ends.
+func setTestStdin(t *testing.T, path string) {
+	stdin, err := os.Open(path)
+	require.NoError(t, err)
+
+	prevStdin := os.Stdin
+	os.Stdin = stdin
+	t.Cleanup(func() {
+		os.Stdin = prevStdin
+		_ = stdin.Close()
+	})
+}
+
+// readTestSource returns the entries of the source keyed by words, every word is needed.
+func readTestSource(t *testing.T, src DictSource) (map[string][]string, *loadStats) {
+	st := &loadStats{}
+	defs := make(map[string][]string)
+	err := src.Read(func(string) bool { return true }, func(wd *wordDef) {
+		key := wd.Word
+		if wd.Pos != "" {
+			key = wd.Pos + "/" + wd.Word
+		}
+		for _, sense := range wd.Senses {
+			defs[key] = append(defs[key], sense.Glosses...)
+		}
+	}, st)
+	require.NoError(t, err)
+
+	return defs, st
+}
@@ -0,0 +1,194 @@
+package helper
+
+import (
+	"crocodiler/internal/croc"
+	"crocodiler/internal/wordmatch"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// selfMask replaces the headword and its inflections in definitions.
+const selfMask = "___"
+
+// maxTemplateDepth limits unwrapping of nested wiki templates.
+const maxTemplateDepth = 3
+
+var (
+	wikiLink     = regexp.MustCompile(`\[\[(?:[^\]|]*\|)?([^\]]*)\]\]`)
+	wikiTemplate = regexp.MustCompile(`\{\{[^{}]*\}\}`)
+	wikiRef      = regexp.MustCompile(`(?s)<ref[^>]*?(?:/>|>.*?</ref>)`)
+	wikiQuotes   = regexp.MustCompile(`'{2,}`)
+	extraSpaces  = regexp.MustCompile(`[ \t]+`)
+)
+
+// stripWikiMarkup removes templates, references and emphasis and keeps the text of links and HTML.
+func stripWikiMarkup(text string) string {
+	text = wikiRef.ReplaceAllString(text, "")
+	for i := 0; i < maxTemplateDepth && strings.Contains(text, "{{"); i++ {
+		text = wikiTemplate.ReplaceAllString(text, "")
+	}
+	text = wikiLink.ReplaceAllString(text, "$1")
+	text = wikiQuotes.ReplaceAllString(text, "")
+	text = stripMarkup(text)
+
+	return strings.TrimSpace(extraSpaces.ReplaceAllString(text, " "))
+}
+
+// sanitizeStats counts definitions of a language changed by sanitizing.
+type sanitizeStats struct {
+	sanitized int
+	resolved  int
+	masked    int
+	dropped   int
+}
+
+func (st *sanitizeStats) String() string {
+	return fmt.Sprintf("Sanitized definitions: %d. Resolved forms: %d. Masked: %d. Dropped senses: %d.",
+		st.sanitized, st.resolved, st.masked, st.dropped)
+}
+
+func isMaskedRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// maskWord replaces the words and their inflections in the text with the mask and returns the number of replacements.
+// Words of a phrase are masked separately, except for short ones which are likely to be articles or prepositions.
+func maskWord(text, mask string, words ...string) (string, int) {
+	var parts []string
+	for _, word := range words {
+		wordParts := strings.FieldsFunc(strings.ToLower(word), func(r rune) bool { return !isMaskedRune(r) })
+		for _, part := range wordParts {
+			if len(wordParts) == 1 || len([]rune(part)) >= wordmatch.MinStem {
+				parts = append(parts, part)
+			}
+		}
+	}
+
+	matches := func(token string) bool {
+		for _, part := range parts {
+			if token == part || (len([]rune(part)) >= wordmatch.MinStem && wordmatch.Matches(token, part)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var res strings.Builder
+	count := 0
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !isMaskedRune(runes[i]) {
+			res.WriteRune(runes[i])
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(runes) && isMaskedRune(runes[j]) {
+			j++
+		}
+
+		token := string(runes[i:j])
+		if matches(strings.ToLower(token)) {
+			res.WriteString(mask)
+			count++
+		} else {
+			res.WriteString(token)
+		}
+		i = j
+	}
+
+	return res.String(), count
+}
+
+// cleanText strips markup and masks the words in the text.
+func cleanText(text string, words []string) (string, bool) {
+	clean := stripWikiMarkup(text)
+	clean, _ = maskWord(clean, selfMask, words...)
+	return clean, clean != text
+}
+
+// hasContent reports whether the masked text has letters besides the mask.
+func hasContent(text string) bool {
+	return strings.ContainsFunc(strings.ReplaceAll(text, selfMask, ""), unicode.IsLetter)
+}
+
+// buildEntry returns the entry of the word. Senses of form-of and alt-of lemmas replace the senses referring to them,
+// the word and the lemmas are masked, and senses left without content are dropped.
+func (lu *langUpdater) buildEntry(word, part string, gloss *glossary, st *sanitizeStats) ([]byte, error) {
+	masked := []string{word}
+	senses := slices.Clip(gloss.senses)
+	resolved := false
+	for _, lemma := range gloss.lemmas {
+		lemmaGloss, err := lu.findGlossary(lemma, part)
+		if err != nil || lemmaGloss == gloss || len(lemmaGloss.senses) == 0 {
+			continue
+		}
+
+		senses = append(senses, lemmaGloss.senses...)
+		masked = append(masked, lemma)
+		resolved = true
+	}
+
+	if !resolved {
+		// the unresolved form-of senses still tell something once the lemma is masked
+		senses = append(senses, gloss.formSenses...)
+		masked = append(masked, gloss.lemmas...)
+	}
+
+	clean := *gloss
+	clean.senses = make([]croc.DictSense, 0, len(senses))
+	changed := resolved
+	var maskedWord bool
+	for _, sense := range senses {
+		text, senseChanged := cleanText(sense.Gloss, masked)
+		changed = changed || senseChanged
+		if !hasContent(text) {
+			st.dropped++
+			continue
+		}
+		maskedWord = maskedWord || strings.Contains(text, selfMask)
+
+		ds := croc.DictSense{Gloss: text}
+		for _, ex := range sense.Examples {
+			text, _ := cleanText(ex, masked)
+			if hasContent(text) {
+				ds.Examples = append(ds.Examples, text)
+			}
+		}
+		for _, syn := range sense.Synonyms {
+			if _, cnt := maskWord(syn, selfMask, masked...); cnt == 0 {
+				ds.Synonyms = append(ds.Synonyms, syn)
+			}
+		}
+
+		clean.senses = append(clean.senses, ds)
+	}
+
+	clean.synonyms = nil
+	for _, syn := range gloss.synonyms {
+		if _, cnt := maskWord(syn, selfMask, masked...); cnt == 0 {
+			clean.synonyms = append(clean.synonyms, syn)
+		}
+	}
+	clean.etymology, _ = cleanText(gloss.etymology, masked)
+
+	if len(clean.senses) == 0 {
+		return nil, fmt.Errorf("definition of word '%s' has no senses left after sanitizing", word)
+	}
+
+	if changed || len(clean.senses) < len(senses) {
+		st.sanitized++
+	}
+	if resolved {
+		st.resolved++
+	}
+	if maskedWord {
+		st.masked++
+	}
+
+	return clean.getEntry()
+}
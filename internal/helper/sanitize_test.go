@@ -0,0 +1,83 @@
+package helper
+
+import (
+	"crocodiler/internal/croc"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+)
+
+func TestMaskWord(t *testing.T) {
+	text, count := maskWord("Plural of apple: an apple-tree fruit. Apples!", "___", "apples")
+	require.Equal(t, "Plural of ___: an ___-tree fruit. ___!", text)
+	require.Equal(t, 3, count)
+
+	text, count = maskWord("An ox and oxen of the oxide", "___", "ox")
+	require.Equal(t, "An ___ and oxen of the oxide", text)
+	require.Equal(t, 1, count)
+
+	text, count = maskWord("A board of directors", "___", "out of order")
+	require.Equal(t, "A board of directors", text)
+	require.Equal(t, 0, count)
+
+	text, count = maskWord("Кошка ловит кошки", "___", "кошка")
+	require.Equal(t, "___ ловит ___", text)
+	require.Equal(t, 2, count)
+}
+
+// testFormsDump has the lemma of "geese" before it, so it is loaded only in the second pass,
+// and the lemma of "mice" after it, so it is loaded in the same pass.
+const testFormsDump = `{"word": "goose", "pos": "noun", "lang_code": "en", "senses": [{"glosses": ["A large water bird, the female goose."]}]}
+{"word": "geese", "pos": "noun", "lang_code": "en", "senses": [{"glosses": ["plural of goose"], "form_of": [{"word": "goose"}]}]}
+{"word": "mice", "pos": "noun", "lang_code": "en", "senses": [{"glosses": ["plural of mouse"], "form_of": [{"word": "mouse"}]}]}
+{"word": "mouse", "pos": "noun", "lang_code": "en", "senses": [{"glosses": ["A small rodent; a mouse of a computer."]}]}
+`
+
+func buildTestEntry(t *testing.T, lu *langUpdater, word string) []string {
+	gloss, err := lu.findGlossary(word, "")
+	require.NoError(t, err)
+
+	value, err := lu.buildEntry(word, "", gloss, &lu.sanitized)
+	require.NoError(t, err)
+
+	entry := croc.ParseDictEntry(value)
+	senses := make([]string, 0, len(entry.Senses))
+	for _, sense := range entry.Senses {
+		senses = append(senses, sense.Gloss)
+	}
+	return senses
+}
+
+func TestBuildEntryLemmas(t *testing.T) {
+	dir := t.TempDir()
+	dumpPath := filepath.Join(dir, "dump.jsonl")
+	writeTestFileAt(t, dumpPath, []byte(testFormsDump))
+	packPath := filepath.Join(dir, "pack.txt")
+	writeTestFileAt(t, packPath, []byte("geese\nmice\n"))
+
+	lang := LanguageConfig{
+		ID:        "en",
+		Dicts:     []DictConfig{{Path: dumpPath}},
+		WordPacks: []WordPackConfig{{ID: "default", Path: packPath}},
+	}
+	lu, err := newLangUpdater(nil, lang)
+	require.NoError(t, err)
+	require.Empty(t, lu.missingLinks())
+
+	require.Equal(t, []string{"A large water bird, the female ___."}, buildTestEntry(t, lu, "geese"))
+	require.Equal(t, []string{"A small rodent; a ___ of a computer."}, buildTestEntry(t, lu, "mice"))
+	require.Equal(t, 2, lu.sanitized.resolved)
+	require.Equal(t, 2, lu.sanitized.masked)
+
+	// the standard input is read once, so the lemma before the form is not loaded
+	setTestStdin(t, dumpPath)
+	lang.Dicts = []DictConfig{{Path: stdinPath}}
+	lu, err = newLangUpdater(nil, lang)
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"goose": true}, lu.missingLinks())
+
+	require.Equal(t, []string{"plural of ___"}, buildTestEntry(t, lu, "geese"))
+	require.Equal(t, []string{"A small rodent; a ___ of a computer."}, buildTestEntry(t, lu, "mice"))
+	require.Equal(t, 1, lu.sanitized.resolved)
+	require.Equal(t, 2, lu.sanitized.masked)
+}
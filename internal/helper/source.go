@@ -0,0 +1,215 @@
+package helper
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"runtime"
+	"strings"
+)
+
+const (
+	formatWiktextract = "wiktextract"
+	formatTSV         = "tsv"
+	formatStarDict    = "stardict"
+	formatDSL         = "dsl"
+)
+
+const (
+	// maxLineSize limits the size of a single dictionary entry.
+	maxLineSize = 64 * 1024 * 1024
+	// progressLines is the number of dump lines between progress reports.
+	progressLines = 1000000
+)
+
+// DictSource streams dictionary entries of a language from a dump.
+type DictSource interface {
+	// Read calls add for every entry of the words for which needed returns true.
+	Read(needed func(word string) bool, add func(wd *wordDef), st *loadStats) error
+}
+
+func newDictSource(cfg DictConfig, langID string) (DictSource, error) {
+	switch cfg.Format {
+	case "", formatWiktextract:
+		paths, err := dumpPaths(cfg.Path)
+		return &wiktextractSource{paths: paths, langID: langID}, err
+	case formatTSV:
+		paths, err := dumpPaths(cfg.Path)
+		return &tsvSource{paths: paths}, err
+	case formatStarDict:
+		paths, err := dumpPaths(cfg.Path)
+		return &starDictSource{paths: paths}, err
+	case formatDSL:
+		paths, err := dumpPaths(cfg.Path)
+		return &dslSource{paths: paths}, err
+	default:
+		return nil, fmt.Errorf("unknown dictionary format %q", cfg.Format)
+	}
+}
+
+type loadStats struct {
+	lines     int
+	malformed int
+	otherLang int
+	words     int
+	peakHeap  uint64
+}
+
+func (st *loadStats) addLine() {
+	st.lines++
+	if st.lines%progressLines == 0 {
+		st.updatePeakHeap()
+		fmt.Printf("Processed %d lines, loaded %d words, memory: ~%d MiB.\n",
+			st.lines, st.words, st.peakHeap/1024/1024)
+	}
+}
+
+func (st *loadStats) updatePeakHeap() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	st.peakHeap = max(st.peakHeap, stats.HeapAlloc)
+}
+
+func newWordDef(word, pos string, senses ...string) *wordDef {
+	wd := &wordDef{
+		Word:   word,
+		Pos:    pos,
+		Senses: make([]wordSense, 0, len(senses)),
+	}
+
+	for _, sense := range senses {
+		wd.Senses = append(wd.Senses, wordSense{Glosses: []string{sense}})
+	}
+
+	return wd
+}
+
+// readLines calls readLine for every line of the dump files.
+func readLines(paths []string, readLine func(line []byte)) error {
+	for _, path := range paths {
+		err := readFileLines(path, readLine)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readFileLines(path string, readLine func(line []byte)) error {
+	file, err := openDump(path)
+	if err != nil {
+		return err
+	}
+	defer func(file io.Closer) {
+		err := file.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(file)
+
+	scanner := bufio.NewScanner(file)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, maxLineSize)
+	for scanner.Scan() {
+		readLine(scanner.Bytes())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+// entryHeader is the part of the wiktextract entry enough to decide whether the entry is needed.
+type entryHeader struct {
+	Word     string
+	LangCode string `json:"lang_code"`
+	Title    string
+}
+
+// wiktextractSource reads JSON lines extracted from Wiktionary by wiktextract.
+type wiktextractSource struct {
+	paths  []string
+	langID string
+}
+
+func (src *wiktextractSource) Read(needed func(string) bool, add func(*wordDef), st *loadStats) error {
+	return readLines(src.paths, func(line []byte) {
+		st.addLine()
+
+		var header entryHeader
+		err := json.Unmarshal(line, &header)
+		if err != nil {
+			st.malformed++
+			return
+		}
+
+		if header.LangCode != "" && header.LangCode != src.langID {
+			st.otherLang++
+			return
+		}
+
+		name := header.Word
+		if name == "" {
+			name = header.Title
+		}
+		if !needed(name) {
+			return
+		}
+
+		wd := &wordDef{}
+		err = json.Unmarshal(line, wd)
+		if err != nil {
+			st.malformed++
+			return
+		}
+
+		add(wd)
+	})
+}
+
+// tsvSource reads word<TAB>pos<TAB>definition lines. The part of speech may be omitted
+// as word<TAB>definition, lines starting with # are comments.
+type tsvSource struct {
+	paths []string
+}
+
+func (src *tsvSource) Read(needed func(string) bool, add func(*wordDef), st *loadStats) error {
+	return readLines(src.paths, func(line []byte) {
+		st.addLine()
+
+		text := strings.TrimRight(string(line), "\r")
+		if text == "" || strings.HasPrefix(text, "#") {
+			return
+		}
+
+		fields := strings.Split(text, "\t")
+		var word, pos, def string
+		switch len(fields) {
+		case 2:
+			word, def = fields[0], fields[1]
+		case 3:
+			word, pos, def = fields[0], fields[1], fields[2]
+		default:
+			st.malformed++
+			return
+		}
+
+		word = strings.TrimSpace(word)
+		def = strings.TrimSpace(strings.ReplaceAll(def, `\n`, "\n"))
+		if word == "" || def == "" {
+			st.malformed++
+			return
+		}
+
+		if !needed(word) {
+			return
+		}
+
+		add(newWordDef(word, strings.TrimSpace(pos), def))
+	})
+}
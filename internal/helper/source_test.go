@@ -0,0 +1,46 @@
+package helper
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTSVSource(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		defs      map[string][]string
+		malformed int
+	}{
+		{
+			name:    "without parts",
+			content: "cat\ta small animal\ndog\ta loyal animal\r\n",
+			defs:    map[string][]string{"cat": {"a small animal"}, "dog": {"a loyal animal"}},
+		},
+		{
+			name:    "with parts",
+			content: "run\tverb\tto move fast\nrun\tnoun\ta fast movement\n",
+			defs:    map[string][]string{"verb/run": {"to move fast"}, "noun/run": {"a fast movement"}},
+		},
+		{
+			name:    "comments and line breaks",
+			content: "# word\tdefinition\n\ncat\tan animal\\nthat purrs\n",
+			defs:    map[string][]string{"cat": {"an animal\nthat purrs"}},
+		},
+		{
+			name:      "malformed",
+			content:   "cat\n\tan animal\ndog\tnoun\t \na\tb\tc\td\n",
+			defs:      map[string][]string{},
+			malformed: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, "dict.tsv", []byte(tt.content))
+			defs, st := readTestSource(t, &tsvSource{paths: []string{path}})
+			require.Equal(t, tt.defs, defs)
+			require.Equal(t, tt.malformed, st.malformed)
+		})
+	}
+}
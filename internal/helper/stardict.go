@@ -0,0 +1,285 @@
+package helper
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// starDictSource reads StarDict dictionaries by paths of their .ifo files.
+// The .idx and .dict files may be gzip compressed as .idx.gz and .dict.dz.
+type starDictSource struct {
+	paths []string
+}
+
+type starDictInfo struct {
+	offsetBits       int
+	sameTypeSequence string
+}
+
+// maxStarDictData is the size limit of a definition, larger sizes come from broken indexes.
+const maxStarDictData = 1 << 20
+
+type starDictRef struct {
+	word   string
+	offset uint64
+	size   uint32
+}
+
+func (src *starDictSource) Read(needed func(string) bool, add func(*wordDef), st *loadStats) error {
+	for _, path := range src.paths {
+		err := readStarDict(path, needed, add, st)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+func readStarDict(path string, needed func(string) bool, add func(*wordDef), st *loadStats) error {
+	info, err := readStarDictInfo(path)
+	if err != nil {
+		return err
+	}
+
+	base := strings.TrimSuffix(path, ".ifo")
+	refs, err := readStarDictIndex(findFile(base, ".idx", ".idx.gz"), info, needed, st)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(refs, func(i, j int) bool { return refs[i].offset < refs[j].offset })
+
+	file, err := openDump(findFile(base, ".dict", ".dict.dz"))
+	if err != nil {
+		return err
+	}
+	defer func(file io.Closer) {
+		err := file.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(file)
+
+	// read definitions in the order of offsets to stream the possibly compressed file once
+	reader := bufio.NewReader(file)
+	var pos uint64
+	// last is the reference whose definition was read last, words sharing it reuse its senses
+	var last *starDictRef
+	var senses []string
+	for i := range refs {
+		ref := &refs[i]
+		if last != nil && ref.offset == last.offset && ref.size == last.size {
+			if len(senses) == 0 {
+				st.malformed++
+				continue
+			}
+			add(newWordDef(ref.word, "", senses...))
+			continue
+		}
+
+		if ref.offset < pos || ref.size > maxStarDictData {
+			st.malformed++
+			continue
+		}
+
+		_, err = io.CopyN(io.Discard, reader, int64(ref.offset-pos))
+		if err != nil {
+			return err
+		}
+
+		data := make([]byte, ref.size)
+		_, err = io.ReadFull(reader, data)
+		if err != nil {
+			return err
+		}
+		pos = ref.offset + uint64(ref.size)
+
+		last = ref
+		senses = parseStarDictData(data, info.sameTypeSequence)
+		if len(senses) == 0 {
+			st.malformed++
+			continue
+		}
+
+		add(newWordDef(ref.word, "", senses...))
+	}
+
+	return nil
+}
+
+// findFile returns the first existing path of the base with one of the extensions.
+func findFile(base string, exts ...string) string {
+	for _, ext := range exts {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext
+		}
+	}
+
+	return base + exts[0]
+}
+
+func readStarDictInfo(path string) (*starDictInfo, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(content), "\n")
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], "StarDict's dict ifo file") {
+		return nil, errors.New("not a StarDict .ifo file")
+	}
+
+	info := &starDictInfo{offsetBits: 32}
+	for _, line := range lines[1:] {
+		key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+		if !found {
+			continue
+		}
+
+		switch key {
+		case "idxoffsetbits":
+			info.offsetBits, err = strconv.Atoi(value)
+			if err != nil || (info.offsetBits != 32 && info.offsetBits != 64) {
+				return nil, fmt.Errorf("invalid idxoffsetbits %q", value)
+			}
+		case "sametypesequence":
+			info.sameTypeSequence = value
+		}
+	}
+
+	return info, nil
+}
+
+func readStarDictIndex(path string, info *starDictInfo, needed func(string) bool, st *loadStats) ([]starDictRef, error) {
+	file, err := openDump(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func(file io.Closer) {
+		err := file.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(file)
+
+	reader := bufio.NewReader(file)
+	buf := make([]byte, info.offsetBits/8+4)
+	var refs []starDictRef
+	for {
+		word, err := reader.ReadString(0)
+		if err == io.EOF && word == "" {
+			return refs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = io.ReadFull(reader, buf)
+		if err != nil {
+			return nil, err
+		}
+
+		st.addLine()
+
+		word = strings.TrimSuffix(word, "\x00")
+		if !needed(word) {
+			continue
+		}
+
+		ref := starDictRef{word: word}
+		if info.offsetBits == 64 {
+			ref.offset = binary.BigEndian.Uint64(buf)
+			ref.size = binary.BigEndian.Uint32(buf[8:])
+		} else {
+			ref.offset = uint64(binary.BigEndian.Uint32(buf))
+			ref.size = binary.BigEndian.Uint32(buf[4:])
+		}
+		refs = append(refs, ref)
+	}
+}
+
+// parseStarDictData returns text fields of the definition data.
+func parseStarDictData(data []byte, sameTypeSequence string) []string {
+	var senses []string
+	addField := func(typ byte, field []byte) {
+		var text string
+		switch typ {
+		case 'm', 'l', 'w':
+			text = string(field)
+		case 'g', 'x', 'h':
+			text = stripMarkup(string(field))
+		default:
+			return
+		}
+
+		text = strings.TrimSpace(text)
+		if text != "" {
+			senses = append(senses, text)
+		}
+	}
+
+	for i := 0; len(data) > 0; i++ {
+		var typ byte
+		last := false
+		if sameTypeSequence != "" {
+			if i == len(sameTypeSequence) {
+				break
+			}
+			typ = sameTypeSequence[i]
+			last = i == len(sameTypeSequence)-1
+		} else {
+			typ = data[0]
+			data = data[1:]
+		}
+
+		var field []byte
+		switch {
+		case last:
+			field, data = data, nil
+		case typ >= 'a' && typ <= 'z':
+			end := strings.IndexByte(string(data), 0)
+			if end < 0 {
+				end = len(data)
+				field, data = data, nil
+			} else {
+				field, data = data[:end], data[end+1:]
+			}
+		default:
+			if len(data) < 4 {
+				return senses
+			}
+			size := int(binary.BigEndian.Uint32(data))
+			data = data[4:]
+			if size > len(data) {
+				return senses
+			}
+			field, data = data[:size], data[size:]
+		}
+
+		addField(typ, field)
+	}
+
+	return senses
+}
+
+var (
+	lineBreakTags = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>`)
+	markupTags    = regexp.MustCompile(`<[^>]*>`)
+)
+
+func stripMarkup(text string) string {
+	text = lineBreakTags.ReplaceAllString(text, "\n")
+	text = markupTags.ReplaceAllString(text, "")
+	return html.UnescapeString(text)
+}
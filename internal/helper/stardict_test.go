@@ -0,0 +1,102 @@
+package helper
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestParseStarDictData(t *testing.T) {
+	sized := func(typ byte, text string) []byte {
+		field := []byte{typ, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(field[1:], uint32(len(text)))
+		return append(field, text...)
+	}
+
+	tests := []struct {
+		name             string
+		data             []byte
+		sameTypeSequence string
+		senses           []string
+	}{
+		{
+			name:             "same type sequence",
+			data:             []byte("a small animal"),
+			sameTypeSequence: "m",
+			senses:           []string{"a small animal"},
+		},
+		{
+			name:             "several fields of the sequence",
+			data:             []byte("a small animal\x00<b>cat</b><br>kitty"),
+			sameTypeSequence: "mh",
+			senses:           []string{"a small animal", "cat\nkitty"},
+		},
+		{
+			name:   "typed fields",
+			data:   []byte("ma small animal\x00g<i>feline</i> &amp; furry\x00"),
+			senses: []string{"a small animal", "feline & furry"},
+		},
+		{
+			name:   "sized fields are skipped",
+			data:   append(sized('W', "wav"), []byte("ma small animal\x00")...),
+			senses: []string{"a small animal"},
+		},
+		{
+			name:   "truncated sized field",
+			data:   append([]byte("ma small animal\x00"), sized('P', "png")[:6]...),
+			senses: []string{"a small animal"},
+		},
+		{
+			name:             "empty fields",
+			data:             []byte(" \x00"),
+			sameTypeSequence: "mm",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.senses, parseStarDictData(tt.data, tt.sameTypeSequence))
+		})
+	}
+}
+
+func TestReadStarDict(t *testing.T) {
+	dict := "a cat" + "dog!" + "a mouse"
+	type ref struct {
+		word         string
+		offset, size uint32
+	}
+	refs := []ref{
+		{"cat", 0, 5},
+		// the definition overlaps the previous one
+		{"kitten", 3, 4},
+		// the same reference as the malformed one is malformed too
+		{"kitty", 3, 4},
+		{"mouse", 9, 7},
+		{"mice", 9, 7},
+		{"huge", 16, maxStarDictData + 1},
+	}
+
+	var idx strings.Builder
+	for _, r := range refs {
+		buf := make([]byte, 8)
+		binary.BigEndian.PutUint32(buf, r.offset)
+		binary.BigEndian.PutUint32(buf[4:], r.size)
+		idx.WriteString(r.word + "\x00" + string(buf))
+	}
+
+	path := writeTestFile(t, "test.ifo", []byte("StarDict's dict ifo file\nversion=2.4.2\nsametypesequence=m\n"))
+	base := strings.TrimSuffix(path, ".ifo")
+	writeTestFileAt(t, base+".idx", []byte(idx.String()))
+	writeTestFileAt(t, base+".dict", []byte(dict))
+
+	defs, st := readTestSource(t, &starDictSource{paths: []string{path}})
+	require.Equal(t, map[string][]string{
+		"cat":   {"a cat"},
+		"mouse": {"a mouse"},
+		"mice":  {"a mouse"},
+	}, defs)
+	require.Equal(t, 3, st.malformed)
+	require.Equal(t, len(refs), st.lines)
+}
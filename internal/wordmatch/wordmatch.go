@@ -0,0 +1,75 @@
+// Package wordmatch matches inflections of words in English and Russian texts
+// without dictionaries of word forms.
+package wordmatch
+
+import "strings"
+
+const (
+	// MinStem is the shortest part of a word that its inflections must keep.
+	MinStem = 4
+	// MaxSuffix is the number of runes an inflection may replace at the end of a word.
+	MaxSuffix = 2
+)
+
+// endings are inflection endings which may replace the ending of a short word.
+var endings = toSet(
+	"s", "es", "ed", "ing", "er", "ers", "est", "ies", "ied",
+	"а", "я", "о", "е", "ё", "ы", "и", "у", "ю", "ь", "й", "ом", "ем", "ём", "ой", "ей", "ам", "ям", "ах", "ях",
+	"ами", "ями", "ов", "ев", "ий", "ый", "ая", "яя", "ое", "ее", "ые", "ие", "ых", "их", "ую", "юю", "ого", "его",
+	"ому", "ему", "ть", "ет", "ит", "ут", "ют", "ат", "ят", "ал", "ла", "ло", "ли", "ешь", "ишь")
+
+func toSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
+
+// isDroppableEnding reports whether the ending of a word may be dropped in its inflections.
+func isDroppableEnding(ending string) bool {
+	return endings[ending] || (len([]rune(ending)) == 1 && strings.ContainsAny(ending, "aeiouyаеёиоуыэюя"))
+}
+
+// Matches reports whether the word is the base word or its inflection. Both must be lowercase.
+func Matches(word, base string) bool {
+	if word == base {
+		return true
+	}
+
+	w, b := []rune(word), []rune(base)
+
+	stemLen := len(b) - MaxSuffix
+	if stemLen < MinStem+MaxSuffix {
+		return matchesShort(word, b)
+	}
+
+	if len(w) < stemLen || len(w) > len(b)+MaxSuffix+1 {
+		return false
+	}
+
+	return string(w[:stemLen]) == string(b[:stemLen])
+}
+
+// matchesShort reports whether the word is the base word with its ending replaced by an inflection
+// ending. Prefixes of short words are not enough: "carpet" is not an inflection of "car".
+func matchesShort(word string, b []rune) bool {
+	for k := 0; k <= MaxSuffix && k < len(b); k++ {
+		stem := string(b[:len(b)-k])
+		if k > 0 && !isDroppableEnding(string(b[len(b)-k:])) {
+			continue
+		}
+
+		ending, ok := strings.CutPrefix(word, stem)
+		if !ok {
+			continue
+		}
+
+		// dropping the ending of the word leaves a word of its own if the stem is too short
+		if endings[ending] || (ending == "" && len(b)-k >= MinStem) {
+			return true
+		}
+	}
+
+	return false
+}
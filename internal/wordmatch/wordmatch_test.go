@@ -0,0 +1,46 @@
+package wordmatch
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		word  string
+		base  string
+		match bool
+	}{
+		{"house", "house", true},
+		{"houses", "house", true},
+		{"housing", "house", true},
+		{"hour", "house", false},
+		{"cats", "cat", true},
+		{"catalog", "cat", false},
+		{"ca", "cat", false},
+		{"дома", "дом", true},
+		{"домашний", "дом", false},
+		{"собаки", "собака", true},
+		{"собак", "собака", true},
+		{"cities", "city", true},
+		{"baked", "bake", true},
+		{"barking", "bark", true},
+		{"bars", "bark", false},
+		{"catch", "cat", false},
+		{"cattle", "cat", false},
+		{"petrol", "pet", false},
+		{"petty", "pet", false},
+		{"carpet", "car", false},
+		{"career", "car", false},
+		{"card", "car", false},
+		{"ca", "car", false},
+		{"translations", "translation", true},
+		{"translated", "translation", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			require.Equal(t, tt.match, Matches(tt.word, tt.base))
+		})
+	}
+}
@@ -1,51 +1,163 @@
 package main
 
 import (
+	"crocodiler/internal/config"
 	"crocodiler/internal/croc"
 	"crocodiler/internal/helper"
 	"fmt"
 	"go.uber.org/zap"
 	"os"
 	"os/signal"
+	"strings"
+	"syscall"
 )
 
 const botArg = "--bot"
 const helpArg = "--help"
 const dictArg = "--dict"
+const genPacksArg = "--gen-packs"
+const configArg = "--config"
+const checkArg = "--check-config"
+const reportsArg = "--reports"
+const difficultyArg = "--difficulty"
+const inspectArg = "--inspect"
+const jsonArg = "--json"
+const dryRunArg = "--dry-run"
+const pruneArg = "--prune"
 
 func printHelp() {
 	fmt.Printf(
 		`
-Usage: %s [option]
+Usage: %s [option] [%s path]
 
 Options are:
 %s	- run Telegram bot (default).
 %s	- update dictionary.
+%s	- generate word packs from the dictionaries by gen_packs filters of the dictionary config.
+%s	- check bot config and exit.
+%s [dir]	- print reported words as CSV and write words excluded by admins to dir.
+%s	- print word statistics and computed difficulty as CSV.
+%s command	- inspect the dictionary, commands are:
+	buckets			- list languages and parts of speech with word counts,
+	lookup lang part word	- look up the word as the bot does, part may be "",
+	missing			- list words of the word packs without definitions.
 %s	- print this help message.
-`, os.Args[0], botArg, dictArg, helpArg)
+
+%s path	- read config from the path instead of %s (bot) or %s (dictionary).
+%s	- print the output of %s as JSON.
+%s	- print changes of %s without writing them.
+%s	- delete definitions of words which are not in any word pack with %s.
+
+Config values can be overridden by environment variables, e.g. %sAI__API_KEY for the bot
+or %sDICT_PATH for the dictionary. Append _FILE to the token or the API key variable
+to read the secret from a file, e.g. %sTG_TOKEN_FILE.
+`, os.Args[0], configArg, botArg, dictArg, genPacksArg, checkArg, reportsArg, difficultyArg, inspectArg, helpArg,
+		configArg, croc.DefaultConfigPath, helper.DefaultConfigPath, jsonArg, inspectArg,
+		dryRunArg, dictArg, pruneArg, dictArg, config.EnvPrefix, config.HelperEnvPrefix, config.EnvPrefix)
 }
 
 func main() {
-	if len(os.Args) == 1 {
-		runBot()
-		return
+	cmd := botArg
+	var cfgPath string
+	var cmdArgs []string
+	var asJSON bool
+	var dictOpts helper.UpdateOptions
+
+	args := os.Args[1:]
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "--") {
+			cmdArgs = append(cmdArgs, args[i])
+			continue
+		}
+
+		switch args[i] {
+		case jsonArg:
+			asJSON = true
+			continue
+		case dryRunArg:
+			dictOpts.DryRun = true
+			continue
+		case pruneArg:
+			dictOpts.Prune = true
+			continue
+		}
+
+		if args[i] != configArg {
+			cmd = args[i]
+			continue
+		}
+
+		i++
+		if i == len(args) {
+			fmt.Printf("Option %s requires a path\n", configArg)
+			os.Exit(1)
+		}
+		cfgPath = args[i]
 	}
 
-	arg := os.Args[1]
-	switch arg {
+	switch cmd {
 	case botArg:
-		runBot()
+		if cfgPath == "" {
+			cfgPath = croc.DefaultConfigPath
+		}
+		runBot(cfgPath)
+	case checkArg:
+		if cfgPath == "" {
+			cfgPath = croc.DefaultConfigPath
+		}
+		if !croc.CheckConfig(cfgPath, os.Stdout) {
+			os.Exit(1)
+		}
+	case reportsArg:
+		if cfgPath == "" {
+			cfgPath = croc.DefaultConfigPath
+		}
+		var excludeDir string
+		if len(cmdArgs) > 0 {
+			excludeDir = cmdArgs[0]
+		}
+		err := croc.ExportReports(cfgPath, os.Stdout, excludeDir)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	case difficultyArg:
+		if cfgPath == "" {
+			cfgPath = croc.DefaultConfigPath
+		}
+		err := croc.ExportDifficulty(cfgPath, os.Stdout)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	case inspectArg:
+		if cfgPath == "" {
+			cfgPath = croc.DefaultConfigPath
+		}
+		err := croc.InspectDict(cfgPath, cmdArgs, asJSON, os.Stdout)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 	case helpArg:
 		printHelp()
 	case dictArg:
-		helper.UpdateDictionary()
+		if cfgPath == "" {
+			cfgPath = helper.DefaultConfigPath
+		}
+		helper.UpdateDictionary(cfgPath, dictOpts)
+	case genPacksArg:
+		if cfgPath == "" {
+			cfgPath = helper.DefaultConfigPath
+		}
+		helper.GeneratePacks(cfgPath)
 	default:
-		fmt.Printf("Unknown option: %s", arg)
+		fmt.Printf("Unknown option: %s", cmd)
 	}
 }
 
-func runBot() {
-	cfg, err := croc.LoadConfig()
+func runBot(cfgPath string) {
+	cfg, err := croc.LoadConfig(cfgPath)
 	if err != nil {
 		panic(err)
 	}
@@ -65,19 +177,8 @@ func runBot() {
 	zap.RedirectStdLog(zapLogger)
 	logger := zapLogger.Sugar()
 
-	wdb := croc.NewWordDB()
-	for _, lang := range cfg.Languages {
-		for _, pack := range lang.WordPacks {
-			ok := wdb.LoadWordPack(pack.Path, lang.ID, pack.ID, pack.Part, lang.Name, pack.Name)
-			if !ok {
-				logger.Warnw("Error loading word pack",
-					"lang_id", lang.ID,
-					"pack_id", pack.ID)
-			}
-		}
-	}
-
-	if len(wdb.GetLanguageIDs()) == 0 {
+	wdb, ok := croc.LoadWordDB(cfg.Languages)
+	if !ok {
 		logger.Panic("no word packs loaded")
 	}
 
@@ -91,7 +192,7 @@ func runBot() {
 		logger.Panic("can't load database")
 	}
 
-	dict, ok := croc.NewDict(cfg.DictPath)
+	dict, ok := croc.LoadDict(cfg)
 	if !ok {
 		logger.Panic("can't load dictionary")
 	}
@@ -107,16 +208,34 @@ func runBot() {
 		}
 	}
 
-	game := croc.NewGame(db, wdb, dict, cfg.GameExp)
-	bot, ok := croc.NewBot(cfg, wdb, db, game, dict, ai)
+	packs, ok := croc.NewChatPacks(db, cfg.ChatPacks)
+	if !ok {
+		logger.Panic("can't load chat word packs")
+	}
+
+	daily := croc.NewDaily(db, wdb, cfg)
+	ratings := croc.NewRatings(db, cfg)
+	game := croc.NewGame(db, wdb, packs, dict, daily, ratings, cfg.GameExp)
+	bot, ok := croc.NewBot(cfgPath, cfg, wdb, db, game, packs, dict, ai, daily, ratings)
 	if !ok {
 		logger.Panic("can't create bot")
 	}
 
+	// the replaced dictionaries are reloaded by the bot, so they don't race with the config reload
+	stopWatch := dict.Watch(croc.DictCheckInterval, bot.ReloadDict)
+	defer stopWatch()
+
 	bot.Start()
 	defer bot.Stop()
 
-	quit := make(chan os.Signal)
-	signal.Notify(quit, os.Interrupt)
-	<-quit
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGHUP)
+	for sig := range quit {
+		if sig != syscall.SIGHUP {
+			break
+		}
+
+		logger.Info("reloading config")
+		bot.Reload()
+	}
 }